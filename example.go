@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/SaoNetwork/sao-client-go/sdk"
 )
@@ -23,35 +24,25 @@ func main() {
 
 	// upload model
 	fmt.Println("upload model")
-	ss, err := client.UploadFile(
+	alias, dataId, cid, err := client.PutFile(
 		ctx,
 		// local file path
 		"foo.txt",
-		// multiaddr of gateway
-		"/ip4/8.222.225.178/udp/5154/quic/webtransport/certhash/uEiAEe-50if6gVaECe0NKhKBhHEMySfy4HtAD2VexGODPaA/certhash/uEiDhqfDJEUnPGh9BMCzoWVTKpA4V3aunIf7F1fCgi1rA5A/p2p/12D3KooWJA2R7RTd6aD2pUdvjN29FdiC8f5edSifXA2tXBcbA2UX",
-		"udp",
+		sdk.PutFileOptions{
+			GroupId:  "example",
+			Duration: 365 * 24 * time.Hour,
+			Delay:    sdk.Epochs(100),
+			Replica:  1,
+			// multiaddr of gateway, the gateway's peer info on chain if empty
+			Multiaddr: "/ip4/8.222.225.178/udp/5154/quic/webtransport/certhash/uEiAEe-50if6gVaECe0NKhKBhHEMySfy4HtAD2VexGODPaA/certhash/uEiDhqfDJEUnPGh9BMCzoWVTKpA4V3aunIf7F1fCgi1rA5A/p2p/12D3KooWJA2R7RTd6aD2pUdvjN29FdiC8f5edSifXA2tXBcbA2UX",
+			Protocol:  "udp",
+		},
 	)
 	if err != nil {
-		fmt.Println("upload file err: ", err)
+		fmt.Println("put file err: ", err)
 		return
 	}
-	fmt.Println("cid", ss[0])
-
-	alias, dataId, err := client.CreateFile(
-		ctx,
-		"foo.txt", // file name
-		ss[0],     // cid
-		"example", // group id
-		365,       // duration days
-		100,       // delay epochs
-		1,         // replica count
-		4,         // file size of foo.txt
-	)
-	if err != nil {
-		fmt.Println("err: ", err)
-		return
-	}
-	fmt.Println("alias: ", alias, "dataId: ", dataId)
+	fmt.Println("alias: ", alias, "dataId: ", dataId, "cid: ", cid)
 	// download link
 	// https://gateway-beta.sao.network/sao/2ce74379-1fdd-11ee-a875-be229d211050
 	// {"nickname": "irene"}
@@ -0,0 +1,43 @@
+package sdk
+
+import (
+	"context"
+
+	api "github.com/SaoNetwork/sao-node/api"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// GatewayApi is the subset of the sao-node gateway JSON-RPC API that SaoClientApi depends on.
+type GatewayApi interface {
+	ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error)
+	ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error)
+	ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error)
+	ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error)
+	ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error)
+	ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error)
+	ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error)
+	ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error)
+	GetNodeAddress(ctx context.Context) (string, error)
+}
+
+// ChainApi is the subset of chain.ChainSvcApi that SaoClientApi depends on.
+type ChainApi interface {
+	GetLastHeight(ctx context.Context) (int64, error)
+	GetNodePeer(ctx context.Context, creator string) (string, error)
+	QueryDidParams(ctx context.Context) (string, error)
+	QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error)
+	GetModel(ctx context.Context, key string) (*modeltypes.QueryGetModelResponse, error)
+	GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error)
+	ListMetaByDid(ctx context.Context, did string) ([]modeltypes.Metadata, error)
+	GetBlock(ctx context.Context, height int64) (*coretypes.ResultBlock, error)
+}
+
+var (
+	_ GatewayApi = (api.SaoApi)(nil)
+	_ ChainApi   = (chain.ChainSvcApi)(nil)
+)
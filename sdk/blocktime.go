@@ -0,0 +1,112 @@
+package sdk
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// blockTimeWindow is the number of recent blocks the block time is averaged over.
+const blockTimeWindow = 100
+
+// maxDays is the largest number of days a time.Duration can hold.
+const maxDays = uint64(math.MaxInt64 / int64(24*time.Hour))
+
+// observedBlockTime is the block time observed on the chain, shared by the views of a client, see SaoClientApi.As.
+type observedBlockTime struct {
+	mu        sync.Mutex
+	blockTime time.Duration
+}
+
+// Epochs is a number of chain blocks, as used for the order timeout.
+type Epochs uint64
+
+// daysToDuration converts the day count taken by the positional apis into a time.Duration.
+func daysToDuration(days uint64) (time.Duration, error) {
+	if days > maxDays {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "duration %d days exceeds %d days", days, maxDays)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// DurationToBlocks converts d into the number of blocks produced in d at the given block time, rounded up
+// so the data is stored for at least d.
+func DurationToBlocks(d time.Duration, blockTime time.Duration) (uint64, error) {
+	if d < 0 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "negative duration %s", d)
+	}
+	if blockTime <= 0 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "invalid block time %s", blockTime)
+	}
+	blocks := uint64(d / blockTime)
+	if d%blockTime != 0 {
+		blocks++
+	}
+	return blocks, nil
+}
+
+// BlockTime returns the block time used to convert durations into blocks: Config.BlockTime if set,
+// otherwise the average block time observed on the connected chain, or chain.Blocktime if it can not be observed.
+func (sc *SaoClientApi) BlockTime(ctx context.Context) time.Duration {
+	if sc.blockTime > 0 {
+		return sc.blockTime
+	}
+
+	sc.observed.mu.Lock()
+	defer sc.observed.mu.Unlock()
+
+	if sc.observed.blockTime > 0 {
+		return sc.observed.blockTime
+	}
+
+	blockTime, err := sc.observeBlockTime(ctx)
+	if err != nil {
+		sc.log.Warnf("failed to observe the chain block time, use %s: %v", chain.Blocktime, err)
+		return chain.Blocktime
+	}
+	sc.observed.blockTime = blockTime
+	return blockTime
+}
+
+// observeBlockTime averages the block time over the last blockTimeWindow blocks.
+func (sc *SaoClientApi) observeBlockTime(ctx context.Context) (time.Duration, error) {
+	height, err := sc.client.GetLastHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	window := int64(blockTimeWindow)
+	if height-window < 1 {
+		window = height - 1
+	}
+	if window < 1 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "not enough blocks at height %d", height)
+	}
+
+	last, err := sc.client.GetBlock(ctx, height)
+	if err != nil {
+		return 0, err
+	}
+	first, err := sc.client.GetBlock(ctx, height-window)
+	if err != nil {
+		return 0, err
+	}
+	if last.Block == nil || first.Block == nil {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "missing block between height %d and %d", height-window, height)
+	}
+
+	blockTime := last.Block.Time.Sub(first.Block.Time) / time.Duration(window)
+	if blockTime <= 0 {
+		return 0, types.Wrapf(types.ErrInvalidParameters, "invalid block time %s between height %d and %d", blockTime, height-window, height)
+	}
+	return blockTime, nil
+}
+
+// storageBlocks converts a storage duration into blocks with the block time of the connected chain.
+func (sc *SaoClientApi) storageBlocks(ctx context.Context, d time.Duration) (uint64, error) {
+	return DurationToBlocks(d, sc.BlockTime(ctx))
+}
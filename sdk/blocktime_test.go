@@ -0,0 +1,115 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestDurationToBlocks(t *testing.T) {
+	cases := []struct {
+		d, blockTime time.Duration
+		want         uint64
+	}{
+		{0, 6 * time.Second, 0},
+		{time.Nanosecond, 6 * time.Second, 1},
+		{6 * time.Second, 6 * time.Second, 1},
+		{6*time.Second + 1, 6 * time.Second, 2},
+		{24 * time.Hour, 6 * time.Second, 14400},
+		{24 * time.Hour, 7 * time.Second, 12343},
+		{math.MaxInt64, time.Nanosecond, math.MaxInt64},
+	}
+	for _, c := range cases {
+		got, err := sdk.DurationToBlocks(c.d, c.blockTime)
+		if err != nil || got != c.want {
+			t.Errorf("%s at %s: got %d, %v, want %d", c.d, c.blockTime, got, err, c.want)
+		}
+	}
+
+	for _, c := range []struct{ d, blockTime time.Duration }{
+		{-time.Second, 6 * time.Second},
+		{time.Hour, 0},
+		{time.Hour, -time.Second},
+	} {
+		if _, err := sdk.DurationToBlocks(c.d, c.blockTime); !errors.Is(err, types.ErrInvalidParameters) {
+			t.Errorf("%s at %s: got %v, want %v", c.d, c.blockTime, err, types.ErrInvalidParameters)
+		}
+	}
+}
+
+func TestDurationInDays(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice", sdk.WithBlockTime(6*time.Second))
+
+	_, dataId, err := client.CreateModel(ctx, `{"a":1}`, "g", 2, 100, "m", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := srv.Chain.GetMeta(ctx, dataId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, err := srv.Chain.GetOrder(ctx, meta.Metadata.OrderId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Duration != 2*14400 {
+		t.Fatalf("got %d blocks, want %d", order.Duration, 2*14400)
+	}
+
+	// the days which do not fit in a time.Duration
+	maxDays := uint64(math.MaxInt64 / int64(24*time.Hour))
+	for _, days := range []uint64{maxDays + 1, math.MaxUint64} {
+		_, _, err := client.CreateModel(ctx, `{"a":2}`, "g", days, 100, "n", 1, false)
+		if !errors.Is(err, types.ErrInvalidParameters) {
+			t.Fatalf("%d days: got %v, want %v", days, err, types.ErrInvalidParameters)
+		}
+	}
+}
+
+func TestBlockTime(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+
+	// a single block
+	if got := client.BlockTime(ctx); got != chain.Blocktime {
+		t.Fatalf("got %s, want %s", got, chain.Blocktime)
+	}
+	// blocks without time between them
+	srv.Store.SetBlockTime(0)
+	srv.Store.AdvanceHeight(10)
+	if got := client.BlockTime(ctx); got != chain.Blocktime {
+		t.Fatalf("got %s, want %s", got, chain.Blocktime)
+	}
+
+	// the fallback is not kept, the observed block time is
+	srv.Store.SetBlockTime(3 * time.Second)
+	if got := client.BlockTime(ctx); got != 3*time.Second {
+		t.Fatalf("got %s, want %s", got, 3*time.Second)
+	}
+	srv.Store.SetBlockTime(4 * time.Second)
+	if got := client.BlockTime(ctx); got != 3*time.Second {
+		t.Fatalf("got %s, want %s", got, 3*time.Second)
+	}
+
+	// the configured block time is not observed
+	configured, err := srv.NewClient(ctx, "alice", home, sdk.WithBlockTime(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configured.BlockTime(ctx); got != time.Minute {
+		t.Fatalf("got %s, want %s", got, time.Minute)
+	}
+}
@@ -0,0 +1,234 @@
+package sdk
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	"github.com/SaoNetwork/sao-node/utils"
+	"github.com/mitchellh/go-homedir"
+)
+
+// Cache stores the content loaded from the gateways, see Config.Cache. The content is stored by cid, and the
+// cid and version of every loaded commit by did, data id and commit id. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Put(key string, value []byte) error
+}
+
+// cachedCommit is what the cache records for a commit, the content itself is stored under its cid.
+type cachedCommit struct {
+	Alias   string `json:"alias"`
+	Version string `json:"version"`
+	Cid     string `json:"cid"`
+}
+
+func cidCacheKey(c string) string {
+	return "cid/" + c
+}
+
+// commitCacheKey is scoped by the did which loaded the commit, as a commit served from the cache
+// is not checked against the permissions of the did.
+func commitCacheKey(did string, dataId string, commitId string) string {
+	return "commit/" + did + "/" + dataId + "/" + commitId
+}
+
+// cachedLoad serves the load request from the cache. A commit id of a data id is served without any network call,
+// unless the content is verified, see Config.VerifyContent. Otherwise the commit to load is looked up on chain,
+// which is cheaper than loading the content again. It returns nil if the content is not cached.
+func (sc *SaoClientApi) cachedLoad(ctx context.Context, request *types.MetadataProposal) (*apitypes.LoadResp, error) {
+	proposal := request.Proposal
+	if !sc.verifyContent && proposal.CommitId != "" && utils.IsDataId(proposal.Keyword) {
+		value, found := sc.cache.Get(commitCacheKey(proposal.Owner, proposal.Keyword, proposal.CommitId))
+		var commit cachedCommit
+		if found && json.Unmarshal(value, &commit) == nil {
+			content, found := sc.cachedContent(commit.Cid)
+			if found {
+				return &apitypes.LoadResp{
+					DataId:   proposal.Keyword,
+					Alias:    commit.Alias,
+					CommitId: proposal.CommitId,
+					Version:  commit.Version,
+					Cid:      commit.Cid,
+					Content:  content,
+				}, nil
+			}
+		}
+	}
+
+	meta, version, err := sc.commitMetadata(ctx, request, proposal.Version, proposal.CommitId)
+	if err != nil {
+		return nil, err
+	}
+	content, found := sc.cachedContent(meta.Cid)
+	if !found {
+		return nil, nil
+	}
+	return &apitypes.LoadResp{
+		DataId:   meta.DataId,
+		Alias:    meta.Alias,
+		CommitId: meta.Commit,
+		Version:  version,
+		Cid:      meta.Cid,
+		Content:  content,
+	}, nil
+}
+
+// cachedContent returns the cached content with the given cid, the content is checked against the cid
+// so that a corrupted entry is a miss.
+func (sc *SaoClientApi) cachedContent(contentCid string) ([]byte, bool) {
+	content, found := sc.cache.Get(cidCacheKey(contentCid))
+	if !found {
+		return nil, false
+	}
+	c, err := CalculateCid(content)
+	if err != nil || c.String() != contentCid {
+		sc.log.Warnf("ignore the cached content of %s, it has cid %s", contentCid, c)
+		return nil, false
+	}
+	return content, true
+}
+
+// cachePut stores the loaded content under its cid, and the commit loaded by did under its data id and commit id.
+// Failures are only logged, the cache is an optimization.
+func (sc *SaoClientApi) cachePut(did string, resp *apitypes.LoadResp) {
+	c, err := CalculateCid(resp.Content)
+	if err != nil {
+		return
+	}
+	err = sc.cache.Put(cidCacheKey(c.String()), resp.Content)
+	if err != nil {
+		sc.log.Warnf("failed to cache the content of %s: %v", resp.DataId, err)
+		return
+	}
+
+	commit, err := json.Marshal(cachedCommit{
+		Alias:   resp.Alias,
+		Version: resp.Version,
+		Cid:     c.String(),
+	})
+	if err != nil {
+		return
+	}
+	err = sc.cache.Put(commitCacheKey(did, resp.DataId, resp.CommitId), commit)
+	if err != nil {
+		sc.log.Warnf("failed to cache the commit %s of %s: %v", resp.CommitId, resp.DataId, err)
+	}
+}
+
+// LRUCache is an in-memory Cache which evicts the least recently used entries beyond a total size.
+type LRUCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+var _ Cache = (*LRUCache)(nil)
+
+// NewLRUCache creates an LRUCache holding up to maxBytes of content.
+func NewLRUCache(maxBytes int64) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		entries:  list.New(),
+		index:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.index[key]
+	if !found {
+		return nil, false
+	}
+	c.entries.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+// Put stores the value, values larger than the cache itself are not stored.
+func (c *LRUCache) Put(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, found := c.index[key]; found {
+		c.remove(element)
+	}
+	if int64(len(value)) > c.maxBytes {
+		return nil
+	}
+
+	c.index[key] = c.entries.PushFront(&lruEntry{key: key, value: value})
+	c.size += int64(len(value))
+	for c.size > c.maxBytes {
+		c.remove(c.entries.Back())
+	}
+	return nil
+}
+
+func (c *LRUCache) remove(element *list.Element) {
+	entry := c.entries.Remove(element).(*lruEntry)
+	delete(c.index, entry.key)
+	c.size -= int64(len(entry.value))
+}
+
+// DiskCache is a Cache storing every entry in a file under a directory. It never evicts entries,
+// the directory can be cleaned up at any time.
+type DiskCache struct {
+	dir string
+}
+
+var _ Cache = (*DiskCache)(nil)
+
+// NewDiskCache creates a DiskCache in dir, creating the directory if needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidPath, err)
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	value, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c *DiskCache) Put(key string, value []byte) error {
+	return writeAtomic(c.path(key), func(w io.Writer) error {
+		_, err := w.Write(value)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+		return nil
+	})
+}
+
+// path names the file of an entry after the hash of its key, as keys contain slashes.
+func (c *DiskCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,177 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := sdk.NewLRUCache(10)
+	has := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			if _, found := cache.Get(key); !found {
+				t.Fatalf("%s was evicted", key)
+			}
+		}
+	}
+	hasNot := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			if _, found := cache.Get(key); found {
+				t.Fatalf("%s was not evicted", key)
+			}
+		}
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Put(key, []byte("123")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	has("a", "b", "c")
+
+	// a was used last, b is the least recently used
+	has("a")
+	cache.Put("d", []byte("123"))
+	hasNot("b")
+	has("a", "c", "d")
+
+	// a replaced entry counts with its new size
+	cache.Put("a", []byte("1"))
+	cache.Put("e", []byte("12"))
+	has("a", "c", "d", "e")
+	if value, _ := cache.Get("a"); string(value) != "1" {
+		t.Fatalf("got %s", value)
+	}
+
+	// an entry larger than the cache is not stored, nor evicts the others
+	cache.Put("big", []byte("12345678901"))
+	hasNot("big")
+	has("a", "c", "d", "e")
+
+	// an entry filling the cache evicts all the others
+	cache.Put("full", []byte("1234567890"))
+	has("full")
+	hasNot("a", "c", "d", "e")
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := sdk.NewDiskCache(dir + "/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := cache.Get("commit/did:key:z/a/b"); found {
+		t.Fatal("found a missing entry")
+	}
+	if err := cache.Put("commit/did:key:z/a/b", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Put("commit/did:key:z/a/b", []byte("new value")); err != nil {
+		t.Fatal(err)
+	}
+
+	// entries persist across instances
+	reopened, err := sdk.NewDiskCache(dir + "/cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, found := reopened.Get("commit/did:key:z/a/b"); !found || string(value) != "new value" {
+		t.Fatalf("got %q, %v", value, found)
+	}
+	if _, found := reopened.Get("commit/did:key:z/a"); found {
+		t.Fatal("found a missing entry")
+	}
+}
+
+func TestCachedLoad(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	newClient(t, srv, home, "bob")
+	dataId := createModel(t, alice, `{"a":1}`, "m")
+
+	cache, err := sdk.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gateway := &countingGateway{Gateway: sdktest.NewGateway(srv.Store, sdktest.GatewayAddress)}
+	cached := func(keyName string) *sdk.SaoClientApi {
+		return sdk.NewSaoClientApiWithBackends(gateway, srv.Chain, keyName, home, sdk.WithCache(cache))
+	}
+	loadAs := func(client *sdk.SaoClientApi, keyword string, commitId string, want string, loads int32) {
+		t.Helper()
+		got, err := client.Load(ctx, keyword, "", commitId, "g")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want || gateway.loads.Load() != loads {
+			t.Fatalf("got %s after %d loads, want %s after %d", got, gateway.loads.Load(), want, loads)
+		}
+	}
+	commit := func() string {
+		meta, err := srv.Chain.GetMeta(ctx, dataId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta.Metadata.Commit
+	}
+	first := commit()
+
+	loadAs(cached("alice"), dataId, "", `{"a":1}`, 1)
+	loadAs(cached("alice"), "m", "", `{"a":1}`, 1)
+	loadAs(cached("alice"), dataId, first, `{"a":1}`, 1)
+
+	// a new commit is looked up on chain and loaded
+	if err := alice.UpdateModelQuick(ctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+	loadAs(cached("alice"), "m", "", `{"a":2}`, 2)
+	loadAs(cached("alice"), dataId, "", `{"a":2}`, 2)
+	loadAs(cached("alice"), dataId, first, `{"a":1}`, 2)
+
+	// the commits are cached per did, bob may not read alice's model from the cache
+	if _, err := cached("bob").Load(ctx, dataId, "", first, "g"); err == nil || gateway.loads.Load() != 2 {
+		t.Fatalf("bob loaded alice's model from the cache, %v", err)
+	}
+
+	// a corrupt content is a miss, and is replaced
+	if err := cache.Put("cid/"+mustCid(t, `{"a":2}`), []byte(`{"a":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	loadAs(cached("alice"), dataId, "", `{"a":2}`, 3)
+	loadAs(cached("alice"), dataId, "", `{"a":2}`, 3)
+
+	// so is a corrupt commit, which is looked up on chain
+	aliceDid := didOf(t, alice, "alice")
+	if err := cache.Put("commit/"+aliceDid+"/"+dataId+"/"+first, []byte("{")); err != nil {
+		t.Fatal(err)
+	}
+	loadAs(cached("alice"), dataId, first, `{"a":1}`, 3)
+}
+
+func TestCachedLoadIsVerified(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	dataId := createModel(t, alice, `{"a":1}`, "m")
+
+	// the tampered content is not cached
+	cache := sdk.NewLRUCache(1 << 20)
+	tampered := newTamperedClient(srv, home, sdk.WithCache(cache), sdk.WithContentVerification(true))
+	if _, err := tampered.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrIntegrity) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrIntegrity)
+	}
+	if _, found := cache.Get("cid/" + mustCid(t, `{"a":1} `)); found {
+		t.Fatal("cached the tampered content")
+	}
+}
@@ -0,0 +1,182 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	saodid "github.com/SaoNetwork/sao-did"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// newClient creates the account keyName in the keyring home and a client of srv signing with it.
+func newClient(t *testing.T, srv *sdktest.Server, home string, keyName string, opts ...sdk.Option) *sdk.SaoClientApi {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := sdktest.CreateAccount(ctx, home, keyName); err != nil {
+		t.Fatal(err)
+	}
+	client, err := srv.NewClient(ctx, keyName, home, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// createModel creates a private model and returns its data id.
+func createModel(t *testing.T, client *sdk.SaoClientApi, content string, alias string) string {
+	t.Helper()
+
+	_, dataId, err := client.CreateModel(context.Background(), content, "g", 1, 100, alias, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dataId
+}
+
+// load loads the last version of a model and fails the test on error.
+func load(t *testing.T, client *sdk.SaoClientApi, keyword string) string {
+	t.Helper()
+
+	content, err := client.Load(context.Background(), keyword, "", "", "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustCid(t *testing.T, content string) string {
+	t.Helper()
+
+	c, err := sdk.CalculateCid([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.String()
+}
+
+func TestCreateUpdateLoad(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	if got := load(t, client, "m"); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+
+	if err := client.UpdateModelQuick(ctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, client, dataId); got != `{"a":2}` {
+		t.Fatalf("got %s", got)
+	}
+	commits, err := client.ShowCommits(ctx, dataId, "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits.Commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits.Commits))
+	}
+
+	if _, err := client.Delete(ctx, dataId); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Load(ctx, dataId, "", "", "g")
+	if !errors.Is(err, sdk.ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrNotFound)
+	}
+}
+
+func TestCreateModelValidation(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+
+	for name, req := range map[string]sdk.CreateModelRequest{
+		"no content":    {GroupId: "g", Name: "m"},
+		"public secret": {Content: "x", GroupId: "g", Name: "m", Encrypt: true, IsPublic: true},
+	} {
+		if _, _, err := client.CreateModelWithRequest(ctx, req); !errors.Is(err, types.ErrInvalidParameters) {
+			t.Errorf("%s: got %v, want %v", name, err, types.ErrInvalidParameters)
+		}
+	}
+}
+
+func TestDidManagerCache(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+
+	// concurrent callers share a single derivation
+	managers := make([]*saodid.DidManager, 10)
+	var wg sync.WaitGroup
+	for i := range managers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			didManager, _, err := client.GetDidManager(ctx, "alice")
+			if err != nil {
+				t.Error(err)
+			}
+			managers[i] = didManager
+		}(i)
+	}
+	wg.Wait()
+	alice := managers[0]
+	for _, didManager := range managers {
+		if didManager != alice {
+			t.Fatal("got several did managers for alice")
+		}
+	}
+
+	// a failure is not cached
+	if _, _, err := client.GetDidManager(ctx, "bob"); err == nil {
+		t.Fatal("got a did manager for a missing key")
+	}
+	if _, err := sdktest.CreateAccount(ctx, home, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	bob, _, err := client.GetDidManager(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(keyName string) *saodid.DidManager {
+		t.Helper()
+		didManager, _, err := client.GetDidManager(ctx, keyName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return didManager
+	}
+	client.InvalidateDidManager("alice")
+	if again := get("alice"); again == alice || again.Id != alice.Id || get("bob") != bob {
+		t.Fatal("InvalidateDidManager did not drop alice only")
+	}
+	client.InvalidateDidManagers()
+	if get("bob") == bob {
+		t.Fatal("InvalidateDidManagers did not drop bob")
+	}
+}
@@ -0,0 +1,344 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	types "github.com/SaoNetwork/sao-node/types"
+	logging "github.com/ipfs/go-log/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Logger is the logging interface used by the sdk, *logging.ZapEventLogger satisfies it.
+type Logger interface {
+	Debugf(template string, args ...interface{})
+	Infof(template string, args ...interface{})
+	Warnf(template string, args ...interface{})
+	Errorf(template string, args ...interface{})
+}
+
+// Config holds everything needed to set up a SaoClientApi.
+type Config struct {
+	// NodeEndpoint is the JSON-RPC endpoint of the gateway.
+	NodeEndpoint string `toml:"NodeEndpoint" yaml:"nodeEndpoint"`
+	// NodeEndpoints are further gateway endpoints to fail over to, NodeEndpoint is preferred if set.
+	NodeEndpoints []string `toml:"NodeEndpoints" yaml:"nodeEndpoints"`
+	// HealthCheckInterval is how long an unavailable gateway is skipped before it is checked again,
+	// 0 means DefaultHealthCheckInterval.
+	HealthCheckInterval time.Duration `toml:"HealthCheckInterval" yaml:"healthCheckInterval"`
+	// GatewayToken is sent as bearer token to the gateway.
+	GatewayToken string `toml:"GatewayToken" yaml:"gatewayToken"`
+	// ChainEndpoint is the tendermint rpc endpoint of the chain.
+	ChainEndpoint string `toml:"ChainEndpoint" yaml:"chainEndpoint"`
+	// ChainWsPath is the websocket path of the chain rpc endpoint.
+	ChainWsPath string `toml:"ChainWsPath" yaml:"chainWsPath"`
+	// ChainHome is the home directory used by the chain client.
+	ChainHome string `toml:"ChainHome" yaml:"chainHome"`
+	// KeyName is the name of the account key in the keyring.
+	KeyName string `toml:"KeyName" yaml:"keyName"`
+	// KeyringHome is the home directory of the keyring.
+	KeyringHome string `toml:"KeyringHome" yaml:"keyringHome"`
+	// SignerEndpoint is the endpoint of a remote signing service holding the keys instead of the keyring,
+	// see NewRemoteSigner. Signer takes precedence over it.
+	SignerEndpoint string `toml:"SignerEndpoint" yaml:"signerEndpoint"`
+	// SignerToken is sent as bearer token to the signing service.
+	SignerToken string `toml:"SignerToken" yaml:"signerToken"`
+	// TransportHome is the local repo used by the file transport.
+	TransportHome string `toml:"TransportHome" yaml:"transportHome"`
+	// Transport is the file transport protocol, udp or tcp.
+	Transport string `toml:"Transport" yaml:"transport"`
+	// DialTimeout bounds setting up the chain connection, 0 means no timeout.
+	DialTimeout time.Duration `toml:"DialTimeout" yaml:"dialTimeout"`
+	// RequestTimeout bounds every single gateway and chain call, 0 means no timeout. The calls to the gateways
+	// of a GatewayPool are bounded per gateway.
+	RequestTimeout time.Duration `toml:"RequestTimeout" yaml:"requestTimeout"`
+	// BlockTime is the chain block time used to convert durations into blocks, 0 means observe it on the chain.
+	BlockTime time.Duration `toml:"BlockTime" yaml:"blockTime"`
+	// Retry is the retry policy of the idempotent gateway and chain calls.
+	Retry RetryPolicy `toml:"Retry" yaml:"retry"`
+	// VerifyContent checks that Load returns the requested commit, or else the latest one on chain, of the requested
+	// data, with the content whose cid the chain recorded for that commit, at the cost of a chain query per load.
+	VerifyContent bool `toml:"VerifyContent" yaml:"verifyContent"`
+
+	Logger Logger `toml:"-" yaml:"-"`
+	// Signer holds the account keys, nil means the remote signer at SignerEndpoint if set, or the keyring at KeyringHome.
+	Signer Signer `toml:"-" yaml:"-"`
+	// Cache keeps the loaded content, nil disables caching. See NewLRUCache and NewDiskCache.
+	Cache Cache `toml:"-" yaml:"-"`
+	// FileTransport uploads the files, nil means the libp2p transport of the sao-node gateways.
+	FileTransport FileTransport `toml:"-" yaml:"-"`
+}
+
+// Option modifies a Config.
+type Option func(cfg *Config)
+
+// environment variables read by Config.LoadEnv.
+const (
+	EnvNodeEndpoint        = "SAO_NODE_ENDPOINT"
+	EnvNodeEndpoints       = "SAO_NODE_ENDPOINTS"
+	EnvGatewayToken        = "SAO_GATEWAY_TOKEN"
+	EnvChainEndpoint       = "SAO_CHAIN_ENDPOINT"
+	EnvChainWsPath         = "SAO_CHAIN_WS_PATH"
+	EnvChainHome           = "SAO_CHAIN_HOME"
+	EnvKeyName             = "SAO_KEY_NAME"
+	EnvKeyringHome         = "SAO_KEYRING_HOME"
+	EnvSignerEndpoint      = "SAO_SIGNER_ENDPOINT"
+	EnvSignerToken         = "SAO_SIGNER_TOKEN"
+	EnvTransportHome       = "SAO_TRANSPORT_HOME"
+	EnvTransport           = "SAO_TRANSPORT"
+	EnvDialTimeout         = "SAO_DIAL_TIMEOUT"
+	EnvRequestTimeout      = "SAO_REQUEST_TIMEOUT"
+	EnvBlockTime           = "SAO_BLOCK_TIME"
+	EnvHealthCheckInterval = "SAO_HEALTH_CHECK_INTERVAL"
+	EnvVerifyContent       = "SAO_VERIFY_CONTENT"
+)
+
+func DefaultConfig() Config {
+	return Config{
+		GatewayToken:  "default token",
+		ChainWsPath:   "/websocket",
+		ChainHome:     "~/.sao",
+		KeyringHome:   "~/.sao",
+		TransportHome: "~/.sao-cli",
+		Transport:     "udp",
+		Retry:         DefaultRetryPolicy(),
+		Logger:        logging.Logger("sao-client"),
+	}
+}
+
+// LoadConfig returns the default config overridden by the given toml or yaml file, if path is not empty,
+// and then by the SAO_* environment variables.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path != "" {
+		err := cfg.LoadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	err := cfg.LoadEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// LoadFile overrides the config with the fields set in a toml or yaml file, chosen by the file extension.
+func (cfg *Config) LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return types.Wrap(types.ErrReadConfigFailed, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		_, err = toml.Decode(string(content), cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, cfg)
+	default:
+		return types.Wrapf(types.ErrInvalidConfig, "unsupported config file %s, expect .toml, .yaml or .yml", path)
+	}
+	if err != nil {
+		return types.Wrap(types.ErrDecodeConfigFailed, err)
+	}
+	return nil
+}
+
+// LoadEnv overrides the config with the SAO_* environment variables which are set,
+// SAO_NODE_ENDPOINTS is a comma separated list.
+func (cfg *Config) LoadEnv() error {
+	for env, field := range map[string]*string{
+		EnvNodeEndpoint:   &cfg.NodeEndpoint,
+		EnvGatewayToken:   &cfg.GatewayToken,
+		EnvChainEndpoint:  &cfg.ChainEndpoint,
+		EnvChainWsPath:    &cfg.ChainWsPath,
+		EnvChainHome:      &cfg.ChainHome,
+		EnvKeyName:        &cfg.KeyName,
+		EnvKeyringHome:    &cfg.KeyringHome,
+		EnvSignerEndpoint: &cfg.SignerEndpoint,
+		EnvSignerToken:    &cfg.SignerToken,
+		EnvTransportHome:  &cfg.TransportHome,
+		EnvTransport:      &cfg.Transport,
+	} {
+		if value, found := os.LookupEnv(env); found {
+			*field = value
+		}
+	}
+
+	if value, found := os.LookupEnv(EnvNodeEndpoints); found {
+		cfg.NodeEndpoints = nil
+		for _, endpoint := range strings.Split(value, ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+				cfg.NodeEndpoints = append(cfg.NodeEndpoints, endpoint)
+			}
+		}
+	}
+
+	for env, field := range map[string]*time.Duration{
+		EnvDialTimeout:         &cfg.DialTimeout,
+		EnvRequestTimeout:      &cfg.RequestTimeout,
+		EnvBlockTime:           &cfg.BlockTime,
+		EnvHealthCheckInterval: &cfg.HealthCheckInterval,
+	} {
+		if value, found := os.LookupEnv(env); found {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return types.Wrapf(types.ErrInvalidConfig, "%s: %v", env, err)
+			}
+			*field = d
+		}
+	}
+
+	if value, found := os.LookupEnv(EnvVerifyContent); found {
+		verify, err := strconv.ParseBool(value)
+		if err != nil {
+			return types.Wrapf(types.ErrInvalidConfig, "%s: %v", EnvVerifyContent, err)
+		}
+		cfg.VerifyContent = verify
+	}
+	return nil
+}
+
+// Validate checks the fields needed to dial the gateway and the chain.
+func (cfg *Config) Validate() error {
+	if len(cfg.nodeEndpoints()) == 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "node endpoint is missing")
+	}
+	if cfg.ChainEndpoint == "" {
+		return types.Wrapf(types.ErrInvalidConfig, "chain endpoint is missing")
+	}
+	if cfg.KeyName == "" {
+		return types.Wrapf(types.ErrInvalidConfig, "key name is missing")
+	}
+	if cfg.Transport != "udp" && cfg.Transport != "tcp" {
+		return types.Wrapf(types.ErrInvalidConfig, "invalid transport %s, expect udp or tcp", cfg.Transport)
+	}
+	if cfg.DialTimeout < 0 || cfg.RequestTimeout < 0 || cfg.HealthCheckInterval < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "timeouts must not be negative")
+	}
+	if cfg.BlockTime < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "block time must not be negative")
+	}
+	return cfg.Retry.validate()
+}
+
+// nodeEndpoints lists NodeEndpoint followed by NodeEndpoints, without empty and duplicate entries.
+func (cfg *Config) nodeEndpoints() []string {
+	var endpoints []string
+	seen := make(map[string]bool)
+	for _, endpoint := range append([]string{cfg.NodeEndpoint}, cfg.NodeEndpoints...) {
+		if endpoint != "" && !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// WithNodeEndpoints adds gateway endpoints to fail over to when the preferred one is unavailable.
+func WithNodeEndpoints(endpoints ...string) Option {
+	return func(cfg *Config) {
+		cfg.NodeEndpoints = append(cfg.NodeEndpoints, endpoints...)
+	}
+}
+
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.HealthCheckInterval = interval
+	}
+}
+
+func WithGatewayToken(token string) Option {
+	return func(cfg *Config) {
+		cfg.GatewayToken = token
+	}
+}
+
+func WithChainWsPath(path string) Option {
+	return func(cfg *Config) {
+		cfg.ChainWsPath = path
+	}
+}
+
+func WithChainHome(home string) Option {
+	return func(cfg *Config) {
+		cfg.ChainHome = home
+	}
+}
+
+func WithTransportHome(home string) Option {
+	return func(cfg *Config) {
+		cfg.TransportHome = home
+	}
+}
+
+// WithTransport selects the file transport protocol, udp or tcp.
+func WithTransport(protocol string) Option {
+	return func(cfg *Config) {
+		cfg.Transport = protocol
+	}
+}
+
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.DialTimeout = timeout
+	}
+}
+
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.RequestTimeout = timeout
+	}
+}
+
+// WithBlockTime sets the chain block time instead of observing it on the chain.
+func WithBlockTime(blockTime time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.BlockTime = blockTime
+	}
+}
+
+// WithRetryPolicy sets the retry policy, use RetryPolicy{} to disable retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *Config) {
+		cfg.Retry = policy
+	}
+}
+
+// WithContentVerification enables or disables the verification of the loaded content, see Config.VerifyContent.
+func WithContentVerification(verify bool) Option {
+	return func(cfg *Config) {
+		cfg.VerifyContent = verify
+	}
+}
+
+// WithCache caches the loaded content, see Config.Cache.
+func WithCache(cache Cache) Option {
+	return func(cfg *Config) {
+		cfg.Cache = cache
+	}
+}
+
+// WithSigner signs with the keys of signer instead of the keyring, see Signer.
+func WithSigner(signer Signer) Option {
+	return func(cfg *Config) {
+		cfg.Signer = signer
+	}
+}
+
+// WithFileTransport replaces the transport used to upload files, e.g. with a fake one in tests.
+func WithFileTransport(transport FileTransport) Option {
+	return func(cfg *Config) {
+		cfg.FileTransport = transport
+	}
+}
+
+func WithLogger(logger Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
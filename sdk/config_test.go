@@ -0,0 +1,194 @@
+package sdk_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+const tomlConfig = `
+NodeEndpoint = "http://gateway:5151/rpc/v0"
+NodeEndpoints = ["http://backup:5151/rpc/v0"]
+ChainEndpoint = "http://chain:26657"
+KeyName = "alice"
+Transport = "tcp"
+RequestTimeout = "30s"
+VerifyContent = true
+
+[Retry]
+MaxAttempts = 5
+InitialBackoff = "1s"
+`
+
+const yamlConfig = `
+nodeEndpoint: http://gateway:5151/rpc/v0
+nodeEndpoints:
+  - http://backup:5151/rpc/v0
+chainEndpoint: http://chain:26657
+keyName: alice
+transport: tcp
+requestTimeout: 30s
+verifyContent: true
+retry:
+  maxAttempts: 5
+  initialBackoff: 1s
+`
+
+// fileConfig is the default config overridden by tomlConfig and yamlConfig.
+func fileConfig() sdk.Config {
+	cfg := sdk.DefaultConfig()
+	cfg.NodeEndpoint = "http://gateway:5151/rpc/v0"
+	cfg.NodeEndpoints = []string{"http://backup:5151/rpc/v0"}
+	cfg.ChainEndpoint = "http://chain:26657"
+	cfg.KeyName = "alice"
+	cfg.Transport = "tcp"
+	cfg.RequestTimeout = 30 * time.Second
+	cfg.VerifyContent = true
+	cfg.Retry.MaxAttempts = 5
+	cfg.Retry.InitialBackoff = time.Second
+	return cfg
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		content string
+		err     error
+	}{
+		{"sdk.toml", tomlConfig, nil},
+		{"sdk.yaml", yamlConfig, nil},
+		{"sdk.yml", yamlConfig, nil},
+		{"SDK.TOML", tomlConfig, nil},
+		{"sdk.json", `{"NodeEndpoint": "http://gateway:5151/rpc/v0"}`, types.ErrInvalidConfig},
+		{"sdk", tomlConfig, types.ErrInvalidConfig},
+		{"invalid.toml", "NodeEndpoint = ", types.ErrDecodeConfigFailed},
+		{"invalid.yaml", "nodeEndpoint: [", types.ErrDecodeConfigFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(dir, c.name)
+			writeFile(t, path, c.content)
+
+			cfg := sdk.DefaultConfig()
+			err := cfg.LoadFile(path)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("got %v, want %v", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fileConfig(); !reflect.DeepEqual(cfg, want) {
+				t.Fatalf("got %+v, want %+v", cfg, want)
+			}
+		})
+	}
+
+	cfg := sdk.DefaultConfig()
+	if err := cfg.LoadFile(filepath.Join(dir, "missing.toml")); !errors.Is(err, types.ErrReadConfigFailed) {
+		t.Fatalf("got %v, want %v", err, types.ErrReadConfigFailed)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sdk.toml")
+	writeFile(t, path, tomlConfig)
+
+	// the environment overrides the file
+	t.Setenv(sdk.EnvNodeEndpoint, "http://env:5151/rpc/v0")
+	t.Setenv(sdk.EnvNodeEndpoints, " http://a:5151/rpc/v0, ,http://b:5151/rpc/v0 ")
+	t.Setenv(sdk.EnvKeyName, "bob")
+	t.Setenv(sdk.EnvRequestTimeout, "1m")
+	t.Setenv(sdk.EnvBlockTime, "6s")
+	t.Setenv(sdk.EnvVerifyContent, "false")
+
+	cfg, err := sdk.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fileConfig()
+	want.NodeEndpoint = "http://env:5151/rpc/v0"
+	want.NodeEndpoints = []string{"http://a:5151/rpc/v0", "http://b:5151/rpc/v0"}
+	want.KeyName = "bob"
+	want.RequestTimeout = time.Minute
+	want.BlockTime = 6 * time.Second
+	want.VerifyContent = false
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("got %+v, want %+v", cfg, want)
+	}
+
+	// without a file
+	cfg, err = sdk.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NodeEndpoint != "http://env:5151/rpc/v0" || cfg.ChainEndpoint != "" || cfg.Transport != "udp" {
+		t.Fatalf("got %+v", cfg)
+	}
+
+	if _, err := sdk.LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, types.ErrReadConfigFailed) {
+		t.Fatalf("got %v, want %v", err, types.ErrReadConfigFailed)
+	}
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	for env, value := range map[string]string{
+		sdk.EnvDialTimeout:         "soon",
+		sdk.EnvRequestTimeout:      "10",
+		sdk.EnvBlockTime:           "6 s",
+		sdk.EnvHealthCheckInterval: "-",
+		sdk.EnvVerifyContent:       "maybe",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			cfg := sdk.DefaultConfig()
+			if err := cfg.LoadEnv(); !errors.Is(err, types.ErrInvalidConfig) {
+				t.Fatalf("got %v, want %v", err, types.ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(cfg *sdk.Config)
+		valid  bool
+	}{
+		{"valid", func(cfg *sdk.Config) {}, true},
+		{"node endpoints only", func(cfg *sdk.Config) { cfg.NodeEndpoint = "" }, true},
+		{"no node endpoint", func(cfg *sdk.Config) { cfg.NodeEndpoint, cfg.NodeEndpoints = "", []string{""} }, false},
+		{"no chain endpoint", func(cfg *sdk.Config) { cfg.ChainEndpoint = "" }, false},
+		{"no key name", func(cfg *sdk.Config) { cfg.KeyName = "" }, false},
+		{"invalid transport", func(cfg *sdk.Config) { cfg.Transport = "quic" }, false},
+		{"negative dial timeout", func(cfg *sdk.Config) { cfg.DialTimeout = -time.Second }, false},
+		{"negative request timeout", func(cfg *sdk.Config) { cfg.RequestTimeout = -time.Second }, false},
+		{"negative health check interval", func(cfg *sdk.Config) { cfg.HealthCheckInterval = -time.Second }, false},
+		{"negative block time", func(cfg *sdk.Config) { cfg.BlockTime = -time.Second }, false},
+		{"negative attempts", func(cfg *sdk.Config) { cfg.Retry.MaxAttempts = -1 }, false},
+		{"negative backoff", func(cfg *sdk.Config) { cfg.Retry.MaxBackoff = -time.Second }, false},
+		{"shrinking backoff", func(cfg *sdk.Config) { cfg.Retry.Multiplier = 0.5 }, false},
+		{"jitter over 1", func(cfg *sdk.Config) { cfg.Retry.Jitter = 1.5 }, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := fileConfig()
+			c.modify(&cfg)
+			err := cfg.Validate()
+			if c.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !c.valid && !errors.Is(err, types.ErrInvalidConfig) {
+				t.Fatalf("got %v, want %v", err, types.ErrInvalidConfig)
+			}
+		})
+	}
+}
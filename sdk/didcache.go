@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+
+	saodid "github.com/SaoNetwork/sao-did"
+)
+
+// identity is what is derived from an account key: the authenticated did manager, the account address and
+// the secret the did key is generated from, which also decrypts the content encrypted for the did.
+type identity struct {
+	didManager *saodid.DidManager
+	address    string
+	secret     []byte
+}
+
+type didEntry struct {
+	done     chan struct{}
+	identity *identity
+	err      error
+}
+
+// didCache keeps one identity per key name. Concurrent callers asking for the same key
+// wait for a single derivation, failed derivations are not cached.
+type didCache struct {
+	lk      sync.Mutex
+	entries map[string]*didEntry
+}
+
+type deriveDidFunc func(ctx context.Context, keyName string) (*identity, error)
+
+func (c *didCache) get(ctx context.Context, keyName string, derive deriveDidFunc) (*identity, error) {
+	c.lk.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*didEntry)
+	}
+	entry, found := c.entries[keyName]
+	if !found {
+		entry = &didEntry{done: make(chan struct{})}
+		c.entries[keyName] = entry
+	}
+	c.lk.Unlock()
+
+	if !found {
+		entry.identity, entry.err = derive(ctx, keyName)
+		close(entry.done)
+		if entry.err != nil {
+			c.remove(keyName, entry)
+		}
+	}
+
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	return entry.identity, nil
+}
+
+func (c *didCache) remove(keyName string, entry *didEntry) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if c.entries[keyName] == entry {
+		delete(c.entries, keyName)
+	}
+}
+
+func (c *didCache) invalidate(keyName string) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	delete(c.entries, keyName)
+}
+
+func (c *didCache) invalidateAll() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.entries = nil
+}
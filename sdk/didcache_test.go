@@ -0,0 +1,133 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingDerive derives an identity per call with the key name as address, once release is closed.
+type countingDerive struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (d *countingDerive) derive(ctx context.Context, keyName string) (*identity, error) {
+	d.calls.Add(1)
+	if d.release != nil {
+		<-d.release
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &identity{address: keyName}, nil
+}
+
+func TestDidCacheDedup(t *testing.T) {
+	ctx := context.Background()
+	var cache didCache
+	d := &countingDerive{release: make(chan struct{})}
+
+	const callers = 20
+	ids := make([]*identity, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := cache.get(ctx, "alice", d.derive)
+			if err != nil {
+				t.Error(err)
+			}
+			ids[i] = id
+		}(i)
+	}
+	// let the callers pile up on the first derivation
+	time.Sleep(50 * time.Millisecond)
+	close(d.release)
+	wg.Wait()
+
+	if d.calls.Load() != 1 {
+		t.Fatalf("derived %d times, want once", d.calls.Load())
+	}
+	for _, id := range ids {
+		if id != ids[0] || id.address != "alice" {
+			t.Fatalf("got %+v, want %+v", id, ids[0])
+		}
+	}
+
+	// another key is derived on its own
+	bob, err := cache.get(ctx, "bob", d.derive)
+	if err != nil || bob.address != "bob" || d.calls.Load() != 2 {
+		t.Fatalf("got %+v, %v, %d derivations", bob, err, d.calls.Load())
+	}
+}
+
+func TestDidCacheFailuresAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	var cache didCache
+	failure := errors.New("no such key")
+	d := &countingDerive{err: failure}
+
+	if _, err := cache.get(ctx, "alice", d.derive); !errors.Is(err, failure) {
+		t.Fatalf("got %v, want %v", err, failure)
+	}
+	d.err = nil
+	id, err := cache.get(ctx, "alice", d.derive)
+	if err != nil || id.address != "alice" || d.calls.Load() != 2 {
+		t.Fatalf("got %+v, %v, %d derivations", id, err, d.calls.Load())
+	}
+}
+
+func TestDidCacheWaitIsCanceled(t *testing.T) {
+	var cache didCache
+	d := &countingDerive{release: make(chan struct{})}
+	defer close(d.release)
+
+	go cache.get(context.Background(), "alice", d.derive)
+	for d.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.get(ctx, "alice", d.derive); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if d.calls.Load() != 1 {
+		t.Fatalf("derived %d times, want once", d.calls.Load())
+	}
+}
+
+func TestDidCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	var cache didCache
+	d := &countingDerive{}
+	get := func(keyName string) *identity {
+		t.Helper()
+		id, err := cache.get(ctx, keyName, d.derive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	alice, bob := get("alice"), get("bob")
+	if get("alice") != alice || d.calls.Load() != 2 {
+		t.Fatalf("derived %d times, want 2", d.calls.Load())
+	}
+
+	cache.invalidate("alice")
+	if get("alice") == alice || get("bob") != bob || d.calls.Load() != 3 {
+		t.Fatalf("derived %d times, want 3", d.calls.Load())
+	}
+
+	cache.invalidateAll()
+	if get("alice") == alice || get("bob") == bob || d.calls.Load() != 5 {
+		t.Fatalf("derived %d times, want 5", d.calls.Load())
+	}
+}
@@ -0,0 +1,281 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// DirManifest is the content of the model PutDir creates for a directory.
+type DirManifest struct {
+	// Files maps the path of every file relative to the directory, with forward slashes, to its file model.
+	Files map[string]DirEntry `json:"files"`
+}
+
+// DirEntry is a file of a DirManifest. Empty files have no file model, so no DataId.
+type DirEntry struct {
+	DataId   string `json:"dataId,omitempty"`
+	Cid      string `json:"cid"`
+	Size     uint64 `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// Paths returns the paths of the files in lexical order.
+func (m *DirManifest) Paths() []string {
+	paths := make([]string, 0, len(m.Files))
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// PutDirOptions describe the models created by PutDir, zero Duration, Delay and Replica take the defaults.
+type PutDirOptions struct {
+	// Name is the alias of the manifest model, the base name of the directory if empty.
+	Name    string
+	GroupId string
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the orders.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+	// Multiaddr is the file transport address of the gateway, the address of Protocol among the ones the gateway
+	// registered on chain if empty.
+	Multiaddr string
+	// Protocol, Progress, Workers and UploadManifest tune the upload, see UploadOptions.
+	Protocol       string
+	Progress       func(UploadProgress)
+	Workers        int
+	UploadManifest string
+}
+
+// PutDir publishes the directory at dir: it uploads its files, creates a file model per file and a manifest model
+// mapping the relative paths to the file models, see DirManifest. If the manifest model exists already, it is
+// updated into a new version and the file models of the unchanged files are kept. The alias of a file model is
+// the manifest model alias, the relative path and the cid of the file, e.g. assets/img/logo.png@Qm...
+// It returns the alias and the data id of the manifest model, with the manifest.
+func (sc *SaoClientApi) PutDir(ctx context.Context, dir string, opts PutDirOptions) (string, string, *DirManifest, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", "", nil, types.Wrap(types.ErrInvalidPath, err)
+	}
+	if !info.IsDir() {
+		return "", "", nil, types.Wrapf(types.ErrInvalidParameters, "%s is not a directory", dir)
+	}
+
+	name := opts.Name
+	if name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", "", nil, types.Wrap(types.ErrInvalidPath, err)
+		}
+		name = filepath.Base(abs)
+	}
+
+	previous, prevResp, err := sc.loadDirManifest(ctx, name, opts.GroupId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", "", nil, err
+	}
+
+	multiaddr := opts.Multiaddr
+	if multiaddr == "" {
+		multiaddr, err = sc.gatewayMultiaddr(ctx, opts.Protocol)
+		if err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	results, err := sc.UploadDir(ctx, dir, multiaddr, UploadOptions{
+		Protocol: opts.Protocol,
+		Progress: opts.Progress,
+		Workers:  opts.Workers,
+		Manifest: opts.UploadManifest,
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	manifest := &DirManifest{Files: make(map[string]DirEntry)}
+	for _, result := range results {
+		entry := DirEntry{
+			Cid:      result.Cid,
+			Size:     uint64(result.Size),
+			MimeType: mimeType(filepath.Join(dir, filepath.FromSlash(result.Path))),
+		}
+
+		if prev, ok := previous.Files[result.Path]; ok && prev.Cid == entry.Cid {
+			entry.DataId = prev.DataId
+		} else if entry.Size > 0 {
+			entry.DataId, err = sc.createDirFile(ctx, path.Join(name, result.Path)+"@"+entry.Cid, entry, opts)
+			if err != nil {
+				return "", "", nil, err
+			}
+		}
+		manifest.Files[result.Path] = entry
+	}
+
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return "", "", nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	if prevResp == nil {
+		alias, dataId, err := sc.CreateModelWithRequest(ctx, CreateModelRequest{
+			Content:  string(content),
+			GroupId:  opts.GroupId,
+			Name:     name,
+			Duration: opts.Duration,
+			Delay:    opts.Delay,
+			Replica:  opts.Replica,
+		})
+		if err != nil {
+			return "", "", nil, err
+		}
+		return alias, dataId, manifest, nil
+	}
+
+	patch, targetCid, size, err := sc.PatchGen(string(prevResp.Content), string(content))
+	if err != nil {
+		return "", "", nil, types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	if patch == "[]" || patch == "" {
+		sc.log.Infof("directory %s is unchanged since commit %s", dir, prevResp.CommitId)
+		return prevResp.Alias, prevResp.DataId, manifest, nil
+	}
+	alias, dataId, _, err := sc.UpdateModelWithRequest(ctx, UpdateModelRequest{
+		Keyword:  prevResp.DataId,
+		GroupId:  opts.GroupId,
+		CommitId: prevResp.CommitId,
+		Patch:    patch,
+		Cid:      targetCid.String(),
+		Size:     uint64(size),
+		Duration: opts.Duration,
+		Delay:    opts.Delay,
+		Replica:  opts.Replica,
+	})
+	if err != nil {
+		return "", "", nil, err
+	}
+	return alias, dataId, manifest, nil
+}
+
+// createDirFile creates the file model of a directory entry. If it exists already, e.g. because an earlier
+// PutDir failed after creating it, its data id is looked up instead.
+func (sc *SaoClientApi) createDirFile(ctx context.Context, alias string, entry DirEntry, opts PutDirOptions) (string, error) {
+	_, dataId, err := sc.CreateFileWithRequest(ctx, CreateFileRequest{
+		FileName: alias,
+		Cid:      entry.Cid,
+		GroupId:  opts.GroupId,
+		Size:     entry.Size,
+		Duration: opts.Duration,
+		Delay:    opts.Delay,
+		Replica:  opts.Replica,
+	})
+	if errors.Is(err, ErrConflict) {
+		commits, showErr := sc.ShowCommits(ctx, alias, opts.GroupId)
+		if showErr != nil {
+			return "", err
+		}
+		return commits.DataId, nil
+	}
+	return dataId, err
+}
+
+// loadDirManifest loads the manifest model with the given alias or data id.
+func (sc *SaoClientApi) loadDirManifest(ctx context.Context, keyword string, groupId string) (*DirManifest, *apitypes.LoadResp, error) {
+	resp, err := sc.loadResponse(ctx, keyword, "", "", groupId)
+	if err != nil {
+		return &DirManifest{}, nil, err
+	}
+
+	manifest := &DirManifest{}
+	err = json.Unmarshal(resp.Content, manifest)
+	if err != nil {
+		return nil, nil, types.Wrapf(types.ErrUnMarshalFailed, "%s is not a directory manifest: %v", keyword, err)
+	}
+	return manifest, resp, nil
+}
+
+// DownloadDir downloads the directory published by PutDir under the manifest model with the given alias or
+// data id into dest, and returns the manifest. The content of every file is checked against its cid, and
+// written atomically, see DownloadToPath.
+func (sc *SaoClientApi) DownloadDir(ctx context.Context, keyword string, groupId string, dest string) (*DirManifest, error) {
+	manifest, _, err := sc.loadDirManifest(ctx, keyword, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range manifest.Paths() {
+		entry := manifest.Files[p]
+		target, err := localPath(dest, p)
+		if err != nil {
+			return nil, err
+		}
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return nil, types.Wrap(types.ErrCreateDirFailed, err)
+		}
+
+		if entry.DataId != "" {
+			_, err = sc.downloadToPath(ctx, entry.DataId, target, DownloadOptions{GroupId: groupId}, entry.Cid)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// empty files have no file model
+		empty, err := CalculateCid(nil)
+		if err != nil {
+			return nil, err
+		}
+		if empty.String() != entry.Cid {
+			return nil, types.Wrapf(types.ErrInvalidCid, "%s has cid %s, expected %s", p, empty, entry.Cid)
+		}
+		err = writeAtomic(target, func(io.Writer) error { return nil })
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// localPath joins a manifest path to dest, rejecting the paths which would escape dest.
+func localPath(dest string, p string) (string, error) {
+	clean := path.Clean(p)
+	if p == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(p, "\\") {
+		return "", types.Wrapf(types.ErrInvalidPath, "invalid path %s in directory manifest", p)
+	}
+	return filepath.Join(dest, filepath.FromSlash(clean)), nil
+}
+
+// mimeType guesses the MIME type of a file from its extension, or from its first bytes.
+func mimeType(file string) string {
+	if t := mime.TypeByExtension(filepath.Ext(file)); t != "" {
+		return t
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	return http.DetectContentType(head[:n])
+}
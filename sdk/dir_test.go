@@ -0,0 +1,58 @@
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+)
+
+func TestPutDir(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dir := filepath.Join(t.TempDir(), "bundle")
+	writeFile(t, filepath.Join(dir, "index.html"), "<html></html>")
+	writeFile(t, filepath.Join(dir, "img", "a.bin"), "\x89PNG\r\n\x1a\nxxxx")
+	writeFile(t, filepath.Join(dir, "empty"), "")
+
+	alias, _, m, err := client.PutDir(ctx, dir, sdk.PutDirOptions{GroupId: "g", Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alias != "bundle" || fmt.Sprint(m.Paths()) != "[empty img/a.bin index.html]" {
+		t.Fatalf("got %s with %v", alias, m.Paths())
+	}
+	if m.Files["empty"].DataId != "" || m.Files["index.html"].DataId == "" {
+		t.Fatalf("got %+v", m.Files)
+	}
+
+	writeFile(t, filepath.Join(dir, "index.html"), "<html>v2</html>")
+	if _, _, _, err := client.PutDir(ctx, dir, sdk.PutDirOptions{GroupId: "g"}); err != nil {
+		t.Fatal(err)
+	}
+	commits, err := client.ShowCommits(ctx, "bundle", "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits.Commits) != 2 {
+		t.Fatalf("got %d commits, want 2", len(commits.Commits))
+	}
+
+	out := t.TempDir()
+	if _, err := client.DownloadDir(ctx, "bundle", "g", out); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{"index.html": "<html>v2</html>", "img/a.bin": "\x89PNG\r\n\x1a\nxxxx", "empty": ""} {
+		got, err := os.ReadFile(filepath.Join(out, path))
+		if err != nil || !bytes.Equal(got, []byte(want)) {
+			t.Fatalf("%s: got %q, %v", path, got, err)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// DefaultMaxDownloadSize is the largest content downloaded when DownloadOptions.MaxSize is zero.
+const DefaultMaxDownloadSize = 256 << 20
+
+// DownloadOptions select the commit to download, the latest one if Version and CommitId are empty.
+type DownloadOptions struct {
+	GroupId string
+	// Version is the version to download, e.g. v0, CommitId takes precedence over it.
+	Version  string
+	CommitId string
+	// MaxSize is the largest content, in bytes, to download, DefaultMaxDownloadSize if zero. The download stops
+	// as soon as the content exceeds it.
+	MaxSize int64
+}
+
+// DownloadResult describes the downloaded commit.
+type DownloadResult struct {
+	DataId   string
+	Alias    string
+	CommitId string
+	Version  string
+	Cid      string
+	Size     int64
+}
+
+// Download writes the content of the model or the file model with the given alias or data id to w. The content
+// is streamed over the file transport of the gateway, or loaded whole with ModelLoad if the transport cannot
+// stream it, into a temporary file where it is checked against the cid the chain recorded for the loaded commit,
+// whether or not Config.VerifyContent is set: nothing is written to w if it does not match. Encrypted content
+// is decrypted in memory, see EncryptContent.
+func (sc *SaoClientApi) Download(ctx context.Context, keyword string, w io.Writer, opts DownloadOptions) (*DownloadResult, error) {
+	tmp, err := os.CreateTemp("", "sao-download-*")
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	result, plaintext, err := sc.download(ctx, keyword, opts, "", tmp)
+	if err != nil {
+		return nil, err
+	}
+	if plaintext != nil {
+		err = writeContent(ctx, w, bytes.NewReader(plaintext))
+	} else {
+		_, err = tmp.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		err = writeContent(ctx, w, tmp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DownloadToPath downloads like Download into the file at path. The content is written to a temporary file next
+// to path, which is renamed to path once complete, so path is either left untouched or holds the whole content.
+func (sc *SaoClientApi) DownloadToPath(ctx context.Context, keyword string, path string, opts DownloadOptions) (*DownloadResult, error) {
+	return sc.downloadToPath(ctx, keyword, path, opts, "")
+}
+
+// downloadToPath is DownloadToPath also checking the content against expectedCid, unless empty.
+func (sc *SaoClientApi) downloadToPath(ctx context.Context, keyword string, path string, opts DownloadOptions, expectedCid string) (*DownloadResult, error) {
+	var result *DownloadResult
+	err := writeAtomicFile(path, func(f *os.File) error {
+		var plaintext []byte
+		var err error
+		result, plaintext, err = sc.download(ctx, keyword, opts, expectedCid, f)
+		if err != nil || plaintext == nil {
+			return err
+		}
+		err = truncate(f)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(plaintext)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// download writes the content into f and checks it against the cid of the loaded commit on chain, and against
+// expectedCid unless empty. Encrypted content is returned decrypted, f then holds the envelope.
+func (sc *SaoClientApi) download(ctx context.Context, keyword string, opts DownloadOptions, expectedCid string, f *os.File) (*DownloadResult, []byte, error) {
+	if keyword == "" {
+		return nil, nil, types.Wrapf(types.ErrInvalidParameters, "keyword is missing")
+	}
+	maxSize := opts.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDownloadSize
+	}
+	version := opts.Version
+	if opts.CommitId != "" {
+		version = ""
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal := saotypes.QueryProposal{
+		Owner:    didManager.Id,
+		Keyword:  keyword,
+		GroupId:  opts.GroupId,
+		CommitId: opts.CommitId,
+		Version:  version,
+	}
+	if !utils.IsDataId(keyword) {
+		proposal.KeywordType = 2
+	}
+
+	var resp *apitypes.LoadResp
+	var c cid.Cid
+	err = sc.withQueryRequest(ctx, didManager, proposal, true, func(gateway GatewayApi, gatewayAddress string, request *types.MetadataProposal) error {
+		err := truncate(f)
+		if err != nil {
+			return err
+		}
+		resp, c, err = sc.loadTo(ctx, gateway, gatewayAddress, request, f, maxSize)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if expectedCid != "" && c.String() != expectedCid {
+		return nil, nil, &IntegrityError{DataId: resp.DataId, CommitId: resp.CommitId, Expected: expectedCid, Actual: c.String()}
+	}
+	err = sc.verifyCommit(ctx, resp, c.String(), keyword, version, opts.CommitId, opts.GroupId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the cid is checked against the envelope of encrypted content, the plaintext is written
+	plaintext, size, err := sc.decryptFile(ctx, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &DownloadResult{
+		DataId:   resp.DataId,
+		Alias:    resp.Alias,
+		CommitId: resp.CommitId,
+		Version:  resp.Version,
+		Cid:      resp.Cid,
+		Size:     size,
+	}, plaintext, nil
+}
+
+// loadTo writes the content request loads to w, up to maxSize bytes, and returns the response without its
+// content along with the cid of the content.
+func (sc *SaoClientApi) loadTo(
+	ctx context.Context,
+	gateway GatewayApi,
+	gatewayAddress string,
+	request *types.MetadataProposal,
+	w io.Writer,
+	maxSize int64,
+) (*apitypes.LoadResp, cid.Cid, error) {
+	resp, content, err := sc.streamLoad(ctx, gatewayAddress, request)
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+	if resp != nil {
+		defer content.Close()
+	} else {
+		loaded, err := gateway.ModelLoad(ctx, request)
+		if err != nil {
+			return nil, cid.Undef, err
+		}
+		resp, content = &loaded, io.NopCloser(bytes.NewReader(loaded.Content))
+		loaded.Content = nil
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), &ctxReader{ctx: ctx, r: io.LimitReader(content, maxSize+1)})
+	if err != nil {
+		return nil, cid.Undef, ctxOr(ctx, classifyError("ModelLoad", err))
+	}
+	if n > maxSize {
+		return nil, cid.Undef, types.Wrapf(types.ErrInvalidParameters, "%s is more than the %d bytes allowed", request.Proposal.Keyword, maxSize)
+	}
+	c, err := sha256Cid(hash.Sum(nil))
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+	return resp, c, nil
+}
+
+// streamLoad sends request as a Sao.ModelLoad over the file transport of the gateway at gatewayAddress, and
+// returns the response without its content and the content as it arrives. The response is nil if the transport
+// cannot stream the load, or if the gateway loaded nothing, as it does not report the errors over its transport:
+// the content is then loaded with ModelLoad.
+func (sc *SaoClientApi) streamLoad(ctx context.Context, gatewayAddress string, request *types.MetadataProposal) (*apitypes.LoadResp, io.ReadCloser, error) {
+	peers, err := sc.client.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := sc.dialTransport(ctx, peers, "")
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		sc.log.Warnf("failed to dial the file transport of %s, load %s whole: %v", gatewayAddress, request.Proposal.Keyword, err)
+		return nil, nil, nil
+	}
+	streamer, ok := conn.(FileTransportStreamer)
+	if !ok {
+		conn.Close()
+		return nil, nil, nil
+	}
+
+	params, err := json.Marshal(request)
+	if err != nil {
+		conn.Close()
+		return nil, nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	stream, err := streamer.Stream(ctx, &types.RpcReq{
+		Method: "Sao.ModelLoad",
+		Params: []string{string(params)},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, nil, ctxOr(ctx, classifyError("ModelLoad", err))
+	}
+	resp, content, err := decodeLoadStream(stream)
+	if err != nil || resp == nil {
+		stream.Close()
+		conn.Close()
+		return nil, nil, ctxOr(ctx, classifyError("ModelLoad", err))
+	}
+	return resp, &streamedContent{Reader: content, stream: stream, conn: conn}, nil
+}
+
+// streamedContent is the content of a load streamed over a file transport connection.
+type streamedContent struct {
+	io.Reader
+	stream io.Closer
+	conn   FileTransportConn
+}
+
+func (c *streamedContent) Close() error {
+	c.stream.Close()
+	return c.conn.Close()
+}
+
+// decryptFile returns the plaintext of the content of f if it is an envelope, nil otherwise, and the size of
+// the decrypted content.
+func (sc *SaoClientApi) decryptFile(ctx context.Context, f *os.File) ([]byte, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	// envelopes are JSON objects, the other content is not read back
+	head := make([]byte, 1)
+	_, err = f.ReadAt(head, 0)
+	if err != nil || head[0] != '{' {
+		return nil, info.Size(), nil
+	}
+
+	content := make([]byte, info.Size())
+	_, err = f.ReadAt(content, 0)
+	if err != nil {
+		return nil, 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	if !IsEncrypted(content) {
+		return nil, info.Size(), nil
+	}
+	plaintext, err := sc.DecryptContent(ctx, content)
+	if err != nil {
+		return nil, 0, err
+	}
+	return plaintext, int64(len(plaintext)), nil
+}
+
+// truncate empties f and rewinds it.
+func truncate(f *os.File) error {
+	err := f.Truncate(0)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+// writeContent copies content to w, stopping once ctx is done.
+func writeContent(ctx context.Context, w io.Writer, content io.Reader) error {
+	_, err := io.Copy(w, &ctxReader{ctx: ctx, r: content})
+	if err != nil {
+		return ctxOr(ctx, types.Wrap(types.ErrWriteFileFailed, err))
+	}
+	return nil
+}
+
+// writeAtomic creates or replaces the file at path with what write writes, through a temporary file
+// in the same directory.
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	return writeAtomicFile(path, func(f *os.File) error {
+		return write(f)
+	})
+}
+
+// writeAtomicFile is writeAtomic handing the temporary file to write.
+func writeAtomicFile(path string, write func(f *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = write(tmp)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Chmod(0644)
+	if err != nil {
+		tmp.Close()
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return types.Wrap(types.ErrCloseFileFailed, err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
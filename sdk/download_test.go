@@ -0,0 +1,192 @@
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestDownload(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	if err := client.UpdateModelQuick(ctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for version, want := range map[string]string{"": `{"a":2}`, "v0": `{"a":1}`, "v1": `{"a":2}`} {
+		var buf bytes.Buffer
+		res, err := client.Download(ctx, dataId, &buf, sdk.DownloadOptions{GroupId: "g", Version: version})
+		if err != nil {
+			t.Fatalf("%q: %v", version, err)
+		}
+		if buf.String() != want || res.Size != int64(len(want)) || res.Alias != "m" {
+			t.Fatalf("%q: got %s and %+v", version, buf.String(), res)
+		}
+	}
+}
+
+func TestDownloadToPath(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	path := filepath.Join(t.TempDir(), "f.bin")
+	content := strings.Repeat("x", 1000)
+	writeFile(t, path, content)
+	_, dataId, _, err := client.PutFile(ctx, path, sdk.PutFileOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := t.TempDir()
+	if _, err := client.DownloadToPath(ctx, dataId, filepath.Join(out, "at-cap"), sdk.DownloadOptions{GroupId: "g", MaxSize: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(out, "at-cap"))
+	if err != nil || string(got) != content {
+		t.Fatalf("got %d bytes, %v", len(got), err)
+	}
+
+	_, err = client.DownloadToPath(ctx, dataId, filepath.Join(out, "over-cap"), sdk.DownloadOptions{GroupId: "g", MaxSize: 999})
+	if !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+	// nothing is left behind by the failed download
+	entries, err := os.ReadDir(out)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got %d files, %v", len(entries), err)
+	}
+}
+
+// countingGateway counts the loads it serves.
+type countingGateway struct {
+	*sdktest.Gateway
+	loads atomic.Int32
+}
+
+func (g *countingGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	g.loads.Add(1)
+	return g.Gateway.ModelLoad(ctx, req)
+}
+
+// uploadTransport dials connections which can only upload.
+type uploadTransport struct {
+	sdk.FileTransport
+}
+
+func (t uploadTransport) Dial(ctx context.Context, multiaddr string, protocol string) (sdk.FileTransportConn, error) {
+	conn, err := t.FileTransport.Dial(ctx, multiaddr, protocol)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ sdk.FileTransportConn }{conn}, nil
+}
+
+// tamperingTransport appends a byte to the content of the loads it streams.
+type tamperingTransport struct {
+	sdk.FileTransport
+}
+
+func (t tamperingTransport) Dial(ctx context.Context, multiaddr string, protocol string) (sdk.FileTransportConn, error) {
+	conn, err := t.FileTransport.Dial(ctx, multiaddr, protocol)
+	if err != nil {
+		return nil, err
+	}
+	return tamperingConn{conn}, nil
+}
+
+type tamperingConn struct {
+	sdk.FileTransportConn
+}
+
+func (c tamperingConn) Stream(ctx context.Context, req *types.RpcReq) (io.ReadCloser, error) {
+	stream, err := c.FileTransportConn.(sdk.FileTransportStreamer).Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var rpcResp types.RpcResp
+	var resp apitypes.LoadResp
+	if err := json.NewDecoder(stream).Decode(&rpcResp); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(rpcResp.Data), &resp); err != nil {
+		return nil, err
+	}
+	resp.Content = append(resp.Content, ' ')
+	data, _ := json.Marshal(resp)
+	rpcResp.Data = string(data)
+	buf, _ := json.Marshal(rpcResp)
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
+
+func TestDownloadStreamsOverTheFileTransport(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+	path := filepath.Join(t.TempDir(), "f.bin")
+	content := strings.Repeat("0123456789\"\\\n", 100000)
+	writeFile(t, path, content)
+	_, dataId, _, err := client.PutFile(ctx, path, sdk.PutFileOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := &countingGateway{Gateway: sdktest.NewGateway(srv.Store, sdktest.GatewayAddress)}
+	download := func(transport sdk.FileTransport) (string, error) {
+		client := sdk.NewSaoClientApiWithBackends(gateway, srv.Chain, "alice", home, sdk.WithFileTransport(transport))
+		var buf bytes.Buffer
+		res, err := client.Download(ctx, dataId, &buf, sdk.DownloadOptions{GroupId: "g", MaxSize: int64(len(content)) + 1})
+		if err == nil && res.Size != int64(buf.Len()) {
+			t.Fatalf("got size %d for %d bytes", res.Size, buf.Len())
+		}
+		return buf.String(), err
+	}
+
+	got, err := download(srv.Transport)
+	if err != nil || got != content || gateway.loads.Load() != 0 {
+		t.Fatalf("got %d bytes, %v, %d loads", len(got), err, gateway.loads.Load())
+	}
+
+	// a transport which cannot stream falls back to ModelLoad
+	got, err = download(uploadTransport{srv.Transport})
+	if err != nil || got != content || gateway.loads.Load() != 1 {
+		t.Fatalf("got %d bytes, %v, %d loads", len(got), err, gateway.loads.Load())
+	}
+
+	// the streamed content is verified
+	got, err = download(tamperingTransport{srv.Transport})
+	if !errors.Is(err, sdk.ErrIntegrity) || got != "" {
+		t.Fatalf("got %d bytes, %v, want %v", len(got), err, sdk.ErrIntegrity)
+	}
+
+	// the cap applies as the content arrives
+	_, err = client.Download(ctx, dataId, io.Discard, sdk.DownloadOptions{GroupId: "g", MaxSize: int64(len(content)) - 1})
+	if !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+
+	// the gateway does not report the failed loads over its transport, ModelLoad does
+	_, err = client.Download(ctx, "missing", io.Discard, sdk.DownloadOptions{GroupId: "g"})
+	if !errors.Is(err, sdk.ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrNotFound)
+	}
+}
@@ -0,0 +1,343 @@
+package sdk
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	didtypes "github.com/SaoNetwork/sao-did/types"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	cid "github.com/ipfs/go-cid"
+)
+
+// maxRenewBlocks is the longest renewal the chain accepts, in blocks.
+const maxRenewBlocks = uint64(60 * 60 * 24 * 365 * 2)
+
+// dryRunMessage is the renew message of the data ids of a dry run.
+const dryRunMessage = "SUCCESS: dry run"
+
+type dryRunKey struct{}
+
+// DryRun collects the requests the mutating methods would have sent within a context of WithDryRun.
+type DryRun struct {
+	mu       sync.Mutex
+	requests []*DryRunRequest
+}
+
+// WithDryRun returns a context in which the mutating methods build, check and sign their proposals but send
+// nothing to the gateway: neither the requests, e.g. ModelCreate or ModelUpdate, nor the file content.
+// The requests are collected in the returned DryRun instead, and the methods return what can be known
+// without the gateway, e.g. the data id and the alias of a new model. Reads, like the chain height or the
+// model loaded by an update, are still served by the gateway and the chain.
+func WithDryRun(ctx context.Context) (context.Context, *DryRun) {
+	d := &DryRun{}
+	return context.WithValue(ctx, dryRunKey{}, d), d
+}
+
+func dryRunFrom(ctx context.Context) *DryRun {
+	d, _ := ctx.Value(dryRunKey{}).(*DryRun)
+	return d
+}
+
+// Requests returns the requests in the order they would have been sent.
+func (d *DryRun) Requests() []*DryRunRequest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]*DryRunRequest{}, d.requests...)
+}
+
+// created tells whether the model with the given data id is created by the dry run.
+func (d *DryRun) created(dataId string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, r := range d.requests {
+		if r.creates() && r.Store.Proposal.DataId == dataId {
+			return true
+		}
+	}
+	return false
+}
+
+// record checks the request and decodes its signature header before collecting it.
+func (d *DryRun) record(r *DryRunRequest) error {
+	owner, signature, err := r.check()
+	if err != nil {
+		return err
+	}
+	r.Header, err = DecodeJwsHeader(signature.Protected)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(r.Header.Kid, owner+"#") {
+		return types.Wrapf(types.ErrInvalidParameters, "%s proposal of %s signed by %s", r.Method, owner, r.Header.Kid)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.requests = append(d.requests, r)
+	return nil
+}
+
+// DryRunRequest is a signed request a mutating method would have sent, see WithDryRun.
+type DryRunRequest struct {
+	// Method is the gateway method the request would have been sent with, e.g. ModelCreate.
+	Method string
+	// Query is the query request sent along the store proposals.
+	Query      *types.MetadataProposal
+	Store      *types.OrderStoreProposal
+	Renew      *types.OrderRenewProposal
+	Terminate  *types.OrderTerminateProposal
+	Permission *types.PermissionProposal
+	// Content is the content of a new model, or the patch of an update.
+	Content []byte
+	// Header is the decoded protected header of the proposal signature.
+	Header didtypes.JWTHeader
+}
+
+// String renders the request for review: the method, the signer and the proposal as indented JSON.
+func (r *DryRunRequest) String() string {
+	var proposal interface{}
+	switch {
+	case r.Store != nil:
+		proposal = r.Store.Proposal
+	case r.Renew != nil:
+		proposal = r.Renew.Proposal
+	case r.Terminate != nil:
+		proposal = r.Terminate.Proposal
+	case r.Permission != nil:
+		proposal = r.Permission.Proposal
+	}
+	content, err := json.MarshalIndent(proposal, "", "  ")
+	if err != nil {
+		content = []byte(err.Error())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s signed by %s (%s)\n", r.Method, r.Header.Kid, r.Header.Alg)
+	if r.Query != nil {
+		fmt.Fprintf(&b, "query valid up to height %d at %s\n", r.Query.Proposal.LastValidHeight, r.Query.Proposal.Gateway)
+	}
+	if len(r.Content) > 0 {
+		fmt.Fprintf(&b, "content of %d bytes\n", len(r.Content))
+	}
+	b.Write(content)
+	return b.String()
+}
+
+// check validates the proposal of the request the way the gateway and the chain would, and returns its owner
+// and signature.
+func (r *DryRunRequest) check() (string, saotypes.JwsSignature, error) {
+	switch {
+	case r.Store != nil:
+		if r.Query == nil || r.Query.JwsSignature.Signature == "" {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "%s without signed query request", r.Method)
+		}
+		return r.Store.Proposal.Owner, r.Store.JwsSignature, checkStoreProposal(r.Store.Proposal, r.creates(), r.content())
+	case r.Renew != nil:
+		p := r.Renew.Proposal
+		if len(p.Data) == 0 {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "data ids is missing")
+		}
+		if p.Duration == 0 || p.Duration > maxRenewBlocks {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "renew duration %d blocks out of [1, %d]", p.Duration, maxRenewBlocks)
+		}
+		if p.Timeout <= 0 {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "invalid timeout %d", p.Timeout)
+		}
+		return p.Owner, r.Renew.JwsSignature, nil
+	case r.Terminate != nil:
+		if r.Terminate.Proposal.DataId == "" {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "data id is missing")
+		}
+		return r.Terminate.Proposal.Owner, r.Terminate.JwsSignature, nil
+	case r.Permission != nil:
+		p := r.Permission.Proposal
+		if p.DataId == "" {
+			return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "data id is missing")
+		}
+		for _, d := range append(append([]string{}, p.ReadonlyDids...), p.ReadwriteDids...) {
+			if !strings.HasPrefix(d, "did:") {
+				return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "invalid did %q", d)
+			}
+		}
+		return p.Owner, r.Permission.JwsSignature, nil
+	}
+	return "", saotypes.JwsSignature{}, types.Wrapf(types.ErrInvalidParameters, "%s without proposal", r.Method)
+}
+
+// creates tells whether the request creates a model.
+func (r *DryRunRequest) creates() bool {
+	return r.Method == "ModelCreate" || r.Method == "ModelCreateFile"
+}
+
+// content returns the content of a new model, nil for the other requests.
+func (r *DryRunRequest) content() []byte {
+	if r.Method == "ModelCreate" {
+		return r.Content
+	}
+	return nil
+}
+
+// checkStoreProposal validates a store proposal, and the content of a new model, if any, against its cid and size.
+func checkStoreProposal(p saotypes.Proposal, create bool, content []byte) error {
+	if !utils.IsDataId(p.DataId) {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid data id %q", p.DataId)
+	}
+	if p.Size_ == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid size")
+	}
+	proposalCid, err := cid.Decode(p.Cid)
+	if err != nil {
+		return types.Wrap(types.ErrInvalidCid, err)
+	}
+	if content != nil {
+		contentCid, err := CalculateCid(content)
+		if err != nil {
+			return err
+		}
+		if !contentCid.Equals(proposalCid) || uint64(len(content)) != p.Size_ {
+			return types.Wrapf(types.ErrInvalidCid, "content is %s of %d bytes, the proposal %s of %d bytes",
+				contentCid, len(content), proposalCid, p.Size_)
+		}
+	}
+	if p.Replica <= 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid replica %d", p.Replica)
+	}
+	if p.Timeout <= 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid timeout %d", p.Timeout)
+	}
+	// the chain prices the order with the duration as int64
+	if p.Duration == 0 || p.Duration > math.MaxInt64 {
+		return types.Wrapf(types.ErrInvalidParameters, "duration %d blocks out of [1, %d]", p.Duration, int64(math.MaxInt64))
+	}
+	if p.Alias == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "alias is missing")
+	}
+	if create && utils.IsDataId(p.Alias) {
+		return types.Wrapf(types.ErrInvalidParameters, "alias %s would be taken for a data id", p.Alias)
+	}
+	return nil
+}
+
+// DecodeJwsHeader decodes the base64url protected header of a proposal signature.
+func DecodeJwsHeader(protected string) (didtypes.JWTHeader, error) {
+	var header didtypes.JWTHeader
+	content, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return header, types.Wrapf(types.ErrInvalidParameters, "invalid protected header: %v", err)
+	}
+	err = json.Unmarshal(content, &header)
+	if err != nil {
+		return header, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	return header, nil
+}
+
+// dryRunGateway collects the mutating gateway calls made within a dry run instead of sending them, see WithDryRun.
+type dryRunGateway struct {
+	GatewayApi
+}
+
+func (g *dryRunGateway) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelCreate(ctx, req, orderProposal, orderId, content)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelCreate", Query: req, Store: orderProposal, Content: content})
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	p := orderProposal.Proposal
+	return apitypes.CreateResp{DataId: p.DataId, Alias: p.Alias, Cid: p.Cid}, nil
+}
+
+func (g *dryRunGateway) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelCreateFile(ctx, req, orderProposal, orderId)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelCreateFile", Query: req, Store: orderProposal})
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	p := orderProposal.Proposal
+	return apitypes.CreateResp{DataId: p.DataId, Alias: p.Alias, Cid: p.Cid}, nil
+}
+
+func (g *dryRunGateway) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelUpdate(ctx, req, orderProposal, orderId, patch)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelUpdate", Query: req, Store: orderProposal, Content: patch})
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	p := orderProposal.Proposal
+	commitIds := strings.Split(p.CommitId, "|")
+	return apitypes.UpdateResp{DataId: p.DataId, CommitId: commitIds[len(commitIds)-1], Alias: p.Alias, Cid: p.Cid}, nil
+}
+
+func (g *dryRunGateway) ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelDelete(ctx, req, isPublish)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelDelete", Terminate: req})
+	if err != nil {
+		return apitypes.DeleteResp{}, err
+	}
+	return apitypes.DeleteResp{DataId: req.Proposal.DataId}, nil
+}
+
+func (g *dryRunGateway) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelRenewOrder(ctx, req, isPublish)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelRenewOrder", Renew: req})
+	if err != nil {
+		return apitypes.RenewResp{}, err
+	}
+	results := make(map[string]string, len(req.Proposal.Data))
+	for _, dataId := range req.Proposal.Data {
+		results[dataId] = dryRunMessage
+	}
+	return apitypes.RenewResp{Results: results}, nil
+}
+
+func (g *dryRunGateway) ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error) {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return g.GatewayApi.ModelUpdatePermission(ctx, req, isPublish)
+	}
+	err := d.record(&DryRunRequest{Method: "ModelUpdatePermission", Permission: req})
+	if err != nil {
+		return apitypes.UpdatePermissionResp{}, err
+	}
+	return apitypes.UpdatePermissionResp{DataId: req.Proposal.DataId}, nil
+}
+
+// dryRunConn acknowledges the chunks of a dry run upload without sending them, the way the gateway would.
+type dryRunConn struct{}
+
+func (dryRunConn) SendChunk(ctx context.Context, chunk *types.FileChunkReq) (string, error) {
+	if len(chunk.Content) == 0 {
+		return chunk.Cid, nil
+	}
+	return chunk.ChunkCid, nil
+}
+
+func (dryRunConn) Close() error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	path := filepath.Join(t.TempDir(), "f.txt")
+	writeFile(t, path, "hello file")
+
+	dctx, dryRun := sdk.WithDryRun(ctx)
+	_, dryId, err := client.CreateModel(dctx, `{"x":1}`, "g", 1, 100, "dry", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.UpdateModelQuick(dctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+	renewed, err := client.Renew(dctx, []string{dataId}, 1, 100)
+	if err != nil || len(renewed) != 1 || renewed[0].Status != sdk.RenewStatusRenewed {
+		t.Fatalf("got %+v, %v", renewed, err)
+	}
+	if err := client.UpdatePermission(dctx, dataId, []string{"did:key:zQ3shabc"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Delete(dctx, dataId); err != nil {
+		t.Fatal(err)
+	}
+	_, fileId, _, err := client.PutFile(dctx, path, sdk.PutFileOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var methods []string
+	for _, r := range dryRun.Requests() {
+		methods = append(methods, r.Method)
+		if !strings.Contains(r.String(), r.Method+" signed by did:key:") {
+			t.Fatalf("got %s", r)
+		}
+	}
+	want := "ModelCreate ModelUpdate ModelRenewOrder ModelUpdatePermission ModelDelete ModelCreateFile"
+	if got := strings.Join(methods, " "); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// nothing reached the gateway
+	if got := load(t, client, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+	for _, id := range []string{dryId, fileId} {
+		if _, err := client.Load(ctx, id, "", "", "g"); !errors.Is(err, sdk.ErrNotFound) {
+			t.Fatalf("got %v, want %v", err, sdk.ErrNotFound)
+		}
+	}
+	if _, ok := srv.Store.Blob(mustCid(t, "hello file")); ok {
+		t.Fatal("the file was uploaded")
+	}
+}
+
+func TestDryRunChecksProposals(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dataId := createModel(t, client, `{"a":1}`, "m")
+
+	dctx, dryRun := sdk.WithDryRun(ctx)
+	_, _, err := client.CreateModelWithRequest(dctx, sdk.CreateModelRequest{Content: "x", GroupId: "g", Name: "aed2548c-c9fb-11f1-9f09-3adc08ccb204"})
+	if !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+	if _, err := client.Renew(dctx, []string{dataId}, 365*3, 100); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+	if n := len(dryRun.Requests()); n != 0 {
+		t.Fatalf("recorded %d invalid requests", n)
+	}
+}
@@ -0,0 +1,413 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	dcrec "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	keywrap "github.com/dvsekhvalnov/jose2go/aes"
+	"github.com/dvsekhvalnov/jose2go/base64url"
+	"github.com/dvsekhvalnov/jose2go/kdf"
+	"github.com/multiformats/go-multibase"
+	"golang.org/x/xerrors"
+)
+
+// algorithms of the envelopes, see Envelope.
+const (
+	EnvelopeType = "sao-jwe"
+	EnvelopeEnc  = "A256GCM"
+	RecipientAlg = "ECDH-ES+A256KW"
+)
+
+// Envelope is encrypted content, in the general JSON serialization of JWE (RFC 7516). The content is encrypted
+// with AES-256-GCM under a random content key, which is wrapped for every recipient with ECDH-ES+A256KW on the
+// secp256k1 key of its did:key. Encrypted models and files hold an envelope instead of the plain content.
+type Envelope struct {
+	Protected  string              `json:"protected"`
+	Recipients []EnvelopeRecipient `json:"recipients"`
+	Iv         string              `json:"iv"`
+	Ciphertext string              `json:"ciphertext"`
+	Tag        string              `json:"tag"`
+}
+
+// EnvelopeRecipient holds the content key wrapped for one did.
+type EnvelopeRecipient struct {
+	Header       RecipientHeader `json:"header"`
+	EncryptedKey string          `json:"encrypted_key"`
+}
+
+type RecipientHeader struct {
+	Alg string `json:"alg"`
+	// Kid is the did of the recipient.
+	Kid string `json:"kid"`
+	// Epk is the ephemeral public key of the key agreement.
+	Epk EphemeralKey `json:"epk"`
+}
+
+// EphemeralKey is a secp256k1 public key as a JWK.
+type EphemeralKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type envelopeHeader struct {
+	Typ string `json:"typ"`
+	Enc string `json:"enc"`
+}
+
+// ParseEnvelope parses encrypted content, it fails if content is not an envelope.
+func ParseEnvelope(content []byte) (*Envelope, error) {
+	var envelope Envelope
+	err := json.Unmarshal(content, &envelope)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "content is not encrypted: %v", err)
+	}
+	var header envelopeHeader
+	protected, err := base64url.Decode(envelope.Protected)
+	if err == nil {
+		err = json.Unmarshal(protected, &header)
+	}
+	if err != nil || header.Typ != EnvelopeType {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "content is not encrypted")
+	}
+	if header.Enc != EnvelopeEnc {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "unsupported content encryption %s", header.Enc)
+	}
+	return &envelope, nil
+}
+
+// IsEncrypted tells whether content is an envelope.
+func IsEncrypted(content []byte) bool {
+	// a cheap check first, as it runs on every loaded content.
+	if !bytes.Contains(content, []byte(`"ciphertext"`)) {
+		return false
+	}
+	_, err := ParseEnvelope(content)
+	return err == nil
+}
+
+// Dids returns the dids of the recipients, who can open the envelope.
+func (e *Envelope) Dids() []string {
+	dids := make([]string, 0, len(e.Recipients))
+	for _, recipient := range e.Recipients {
+		dids = append(dids, recipient.Header.Kid)
+	}
+	return dids
+}
+
+// EncryptContent encrypts content for the did of the client and the given recipients, which must be did:key dids.
+// It returns the envelope to store instead of the content.
+func (sc *SaoClientApi) EncryptContent(ctx context.Context, content []byte, recipients ...string) ([]byte, error) {
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+	return sealEnvelope(content, append([]string{didManager.Id}, recipients...))
+}
+
+// DecryptContent opens an envelope with the key of the client's did. It fails with ErrPermissionDenied
+// if the did is not a recipient.
+func (sc *SaoClientApi) DecryptContent(ctx context.Context, content []byte) ([]byte, error) {
+	envelope, err := ParseEnvelope(content)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := sc.contentKey(ctx, sc.KeyName(ctx), envelope)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.open(cek)
+}
+
+// AddRecipients returns the envelope with the content key also wrapped for the given dids, the content
+// is not encrypted again. The client's did must be a recipient already.
+func (sc *SaoClientApi) AddRecipients(ctx context.Context, content []byte, recipients ...string) ([]byte, error) {
+	envelope, err := ParseEnvelope(content)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := sc.contentKey(ctx, sc.KeyName(ctx), envelope)
+	if err != nil {
+		return nil, err
+	}
+	err = envelope.addRecipients(cek, recipients)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEnvelope(envelope)
+}
+
+// decryptLoaded decrypts loaded content if it is encrypted, and returns it as it is otherwise.
+func (sc *SaoClientApi) decryptLoaded(ctx context.Context, content []byte) ([]byte, error) {
+	if !IsEncrypted(content) {
+		return content, nil
+	}
+	return sc.DecryptContent(ctx, content)
+}
+
+// sealPatch turns the request of an update of the decrypted content into the update of the envelope: it applies
+// the patch to the decrypted content, seals the result for the recipients of the envelope and replaces the patch,
+// the cid and the size with the ones of the new envelope.
+func (sc *SaoClientApi) sealPatch(ctx context.Context, req *UpdateModelRequest, content []byte) error {
+	if !IsEncrypted(content) {
+		return types.Wrapf(types.ErrInvalidParameters, "content of %s is not encrypted", req.Keyword)
+	}
+	envelope, err := ParseEnvelope(content)
+	if err != nil {
+		return err
+	}
+	cek, err := sc.contentKey(ctx, sc.KeyName(ctx), envelope)
+	if err != nil {
+		return err
+	}
+	plaintext, err := envelope.open(cek)
+	if err != nil {
+		return err
+	}
+
+	patched, err := utils.ApplyPatch(plaintext, []byte(req.Patch))
+	if err != nil {
+		return types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	patchedCid, err := CalculateCid(patched)
+	if err != nil {
+		return err
+	}
+	if patchedCid.String() != req.Cid || uint64(len(patched)) != req.Size {
+		return types.Wrapf(types.ErrInvalidCid, "patched content has cid %s and %d bytes, expected %s and %d bytes",
+			patchedCid, len(patched), req.Cid, req.Size)
+	}
+
+	sealed, err := sealEnvelope(patched, envelope.Dids())
+	if err != nil {
+		return err
+	}
+	patch, sealedCid, size, err := sc.PatchGen(string(content), string(sealed))
+	if err != nil {
+		return types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	req.Patch, req.Cid, req.Size = patch, sealedCid.String(), uint64(size)
+	return nil
+}
+
+// contentKey unwraps the content key of the envelope with the key of the did of keyName.
+func (sc *SaoClientApi) contentKey(ctx context.Context, keyName string, envelope *Envelope) ([]byte, error) {
+	id, err := sc.dids.get(ctx, keyName, sc.deriveIdentity)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	for _, recipient := range envelope.Recipients {
+		if recipient.Header.Kid != id.didManager.Id {
+			continue
+		}
+		if recipient.Header.Alg != RecipientAlg {
+			return nil, types.Wrapf(types.ErrInvalidParameters, "unsupported key management %s", recipient.Header.Alg)
+		}
+		ephemeral, err := parseEphemeralKey(recipient.Header.Epk)
+		if err != nil {
+			return nil, err
+		}
+		encryptedKey, err := base64url.Decode(recipient.EncryptedKey)
+		if err != nil {
+			return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+		}
+
+		key := dcrec.PrivKeyFromBytes(secp256k1.GenPrivKeyFromSecret(id.secret).Key)
+		cek, err := keywrap.KeyUnwrap(encryptedKey, keyEncryptionKey(dcrec.GenerateSharedSecret(key, ephemeral)))
+		if err != nil {
+			return nil, xerrors.Errorf("%w: failed to unwrap the content key: %v", ErrIntegrity, err)
+		}
+		return cek, nil
+	}
+	return nil, xerrors.Errorf("%w: %s is not a recipient of the content", ErrPermissionDenied, id.didManager.Id)
+}
+
+// sealEnvelope encrypts content under a new content key wrapped for the given dids.
+func sealEnvelope(content []byte, dids []string) ([]byte, error) {
+	cek := make([]byte, 32)
+	_, err := rand.Read(cek)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+
+	header, err := json.Marshal(envelopeHeader{Typ: EnvelopeType, Enc: EnvelopeEnc})
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	protected := base64url.Encode(header)
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(iv)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	sealed := gcm.Seal(nil, iv, content, []byte(protected))
+	ciphertext, tag := sealed[:len(content)], sealed[len(content):]
+
+	envelope := &Envelope{
+		Protected:  protected,
+		Iv:         base64url.Encode(iv),
+		Ciphertext: base64url.Encode(ciphertext),
+		Tag:        base64url.Encode(tag),
+	}
+	err = envelope.addRecipients(cek, dids)
+	if err != nil {
+		return nil, err
+	}
+	return marshalEnvelope(envelope)
+}
+
+// open decrypts the content of the envelope with the unwrapped content key.
+func (e *Envelope) open(cek []byte) ([]byte, error) {
+	iv, err := base64url.Decode(e.Iv)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	ciphertext, err := base64url.Decode(e.Ciphertext)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	tag, err := base64url.Decode(e.Tag)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != gcm.NonceSize() {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "invalid iv length %d", len(iv))
+	}
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(e.Protected))
+	if err != nil {
+		return nil, xerrors.Errorf("%w: failed to decrypt the content: %v", ErrIntegrity, err)
+	}
+	return plaintext, nil
+}
+
+// addRecipients wraps cek for the dids which are not recipients yet.
+func (e *Envelope) addRecipients(cek []byte, dids []string) error {
+	existing := make(map[string]bool)
+	for _, did := range e.Dids() {
+		existing[did] = true
+	}
+
+	for _, did := range dids {
+		if existing[did] {
+			continue
+		}
+		existing[did] = true
+
+		pub, err := didKeyPublicKey(did)
+		if err != nil {
+			return err
+		}
+		ephemeral, err := dcrec.GeneratePrivateKey()
+		if err != nil {
+			return types.Wrap(types.ErrInvalidParameters, err)
+		}
+		encryptedKey, err := keywrap.KeyWrap(cek, keyEncryptionKey(dcrec.GenerateSharedSecret(ephemeral, pub)))
+		if err != nil {
+			return types.Wrap(types.ErrInvalidParameters, err)
+		}
+
+		epk := ephemeral.PubKey().SerializeUncompressed()
+		e.Recipients = append(e.Recipients, EnvelopeRecipient{
+			Header: RecipientHeader{
+				Alg: RecipientAlg,
+				Kid: did,
+				Epk: EphemeralKey{
+					Kty: "EC",
+					Crv: "secp256k1",
+					X:   base64url.Encode(epk[1:33]),
+					Y:   base64url.Encode(epk[33:]),
+				},
+			},
+			EncryptedKey: base64url.Encode(encryptedKey),
+		})
+	}
+	return nil
+}
+
+// didKeyPublicKey extracts the secp256k1 public key of a did:key did, other did methods can not be recipients.
+func didKeyPublicKey(did string) (*dcrec.PublicKey, error) {
+	if !strings.HasPrefix(did, "did:key:") {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%s can not be a recipient, only did:key dids can", did)
+	}
+	_, decoded, err := multibase.Decode(strings.TrimPrefix(did, "did:key:"))
+	if err != nil || len(decoded) < 2 || decoded[0] != 0xe7 || decoded[1] != 0x01 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%s is not a secp256k1 did:key", did)
+	}
+	pub, err := dcrec.ParsePubKey(decoded[2:])
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%s has an invalid public key: %v", did, err)
+	}
+	return pub, nil
+}
+
+func parseEphemeralKey(epk EphemeralKey) (*dcrec.PublicKey, error) {
+	if epk.Kty != "EC" || epk.Crv != "secp256k1" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "unsupported ephemeral key %s %s", epk.Kty, epk.Crv)
+	}
+	x, err := base64url.Decode(epk.X)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	y, err := base64url.Decode(epk.Y)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	pub, err := dcrec.ParsePubKey(append(append([]byte{0x04}, x...), y...))
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid ephemeral key: %v", err)
+	}
+	return pub, nil
+}
+
+// keyEncryptionKey derives the AES-256 key wrapping key from the shared secret with the Concat KDF of
+// RFC 7518 section 4.6, without party info.
+func keyEncryptionKey(sharedSecret []byte) []byte {
+	return kdf.DeriveConcatKDF(256, sharedSecret, lengthPrefixed([]byte(RecipientAlg)), lengthPrefixed(nil), lengthPrefixed(nil),
+		binary.BigEndian.AppendUint32(nil, 256), nil, sha256.New())
+}
+
+func lengthPrefixed(data []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	return gcm, nil
+}
+
+func marshalEnvelope(envelope *Envelope) ([]byte, error) {
+	content, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return content, nil
+}
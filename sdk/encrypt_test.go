@@ -0,0 +1,182 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func didOf(t *testing.T, client *sdk.SaoClientApi, keyName string) string {
+	t.Helper()
+
+	didManager, _, err := client.GetDidManager(context.Background(), keyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return didManager.Id
+}
+
+func TestEncryptedModelSharing(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	bob := newClient(t, srv, home, "bob")
+	bobDid := didOf(t, bob, "bob")
+
+	_, dataId, err := alice.CreateModelWithRequest(ctx, sdk.CreateModelRequest{Content: `{"secret":1}`, GroupId: "g", Name: "s", Encrypt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, alice, "s"); got != `{"secret":1}` {
+		t.Fatalf("got %s", got)
+	}
+	if _, err := bob.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrPermissionDenied) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrPermissionDenied)
+	}
+
+	// a grant alone does not let bob decrypt the content
+	if err := alice.UpdatePermission(ctx, dataId, []string{bobDid}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrPermissionDenied) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrPermissionDenied)
+	}
+
+	req := sdk.UpdatePermissionRequest{DataId: dataId, ReadonlyDids: []string{bobDid}, Encrypted: true}
+	if err := alice.UpdatePermissionWithRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, bob, dataId); got != `{"secret":1}` {
+		t.Fatalf("got %s", got)
+	}
+	// sharing again with the same recipients changes nothing
+	if err := alice.UpdatePermissionWithRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	req.ReadonlyDids = []string{"did:sid:abc"}
+	if err := alice.UpdatePermissionWithRequest(ctx, req); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+
+	// the plain content can not be shared as encrypted content
+	plainId := createModel(t, alice, `{"plain":1}`, "p")
+	err = alice.UpdatePermissionWithRequest(ctx, sdk.UpdatePermissionRequest{DataId: plainId, ReadonlyDids: []string{bobDid}, Encrypted: true})
+	if err == nil {
+		t.Fatal("shared plain content as encrypted content")
+	}
+}
+
+func TestEncryptedFileRecipients(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	bob := newClient(t, srv, home, "bob")
+	bobDid := didOf(t, bob, "bob")
+	path := filepath.Join(t.TempDir(), "f.txt")
+	writeFile(t, path, "hello file")
+
+	_, dataId, _, err := alice.PutFile(ctx, path, sdk.PutFileOptions{GroupId: "g", Encrypt: true, Recipients: []string{bobDid}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, ok := srv.Store.Blob(mustCid(t, "hello file")); ok {
+		t.Fatalf("the gateway stored the plain content %q", content)
+	}
+	if _, err := bob.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrPermissionDenied) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrPermissionDenied)
+	}
+
+	if err := alice.UpdatePermission(ctx, dataId, []string{bobDid}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, bob, dataId); got != "hello file" {
+		t.Fatalf("got %s", got)
+	}
+}
+
+func TestUpdateEncryptedModel(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	bob := newClient(t, srv, home, "bob")
+	bobDid := didOf(t, bob, "bob")
+
+	_, dataId, err := alice.CreateModelWithRequest(ctx, sdk.CreateModelRequest{Content: `{"secret":1}`, GroupId: "g", Name: "s", Encrypt: true, Recipients: []string{bobDid}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.UpdatePermission(ctx, dataId, []string{bobDid}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stored content stays an envelope for the same recipients
+	stored := func() []byte {
+		t.Helper()
+
+		meta, err := srv.Chain.GetMeta(ctx, dataId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := srv.Store.Blob(meta.Metadata.Cid)
+		if !sdk.IsEncrypted(content) {
+			t.Fatalf("the gateway stores the plain content %q", content)
+		}
+		return content
+	}
+
+	if err := alice.UpdateModelQuick(ctx, dataId, []byte(`{"secret":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+	stored()
+	if got := load(t, bob, dataId); got != `{"secret":2}` {
+		t.Fatalf("got %s", got)
+	}
+
+	res, err := alice.Download(ctx, dataId, io.Discard, sdk.DownloadOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, c, size, err := alice.PatchGen(`{"secret":2}`, `{"secret":3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := sdk.UpdateModelRequest{Keyword: dataId, GroupId: "g", CommitId: res.CommitId, Patch: patch, Cid: c.String(), Size: uint64(size), Encrypt: true}
+	if _, _, _, err := alice.UpdateModelWithRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := sdk.ParseEnvelope(stored())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envelope.Dids()) != 2 {
+		t.Fatalf("got recipients %v", envelope.Dids())
+	}
+	if got := load(t, alice, dataId); got != `{"secret":3}` {
+		t.Fatalf("got %s", got)
+	}
+
+	// Encrypt needs an encrypted model
+	plainId := createModel(t, alice, `{"plain":1}`, "p")
+	res, err = alice.Download(ctx, plainId, io.Discard, sdk.DownloadOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, c, size, _ = alice.PatchGen(`{"plain":1}`, `{"plain":2}`)
+	req = sdk.UpdateModelRequest{Keyword: plainId, GroupId: "g", CommitId: res.CommitId, Patch: patch, Cid: c.String(), Size: uint64(size), Encrypt: true}
+	if _, _, _, err := alice.UpdateModelWithRequest(ctx, req); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+}
@@ -0,0 +1,267 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	"github.com/filecoin-project/go-jsonrpc"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// sentinel errors the failures reported by the gateway and the chain are classified into, use errors.Is to test for them.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrNoChanges          = errors.New("no changes")
+	ErrConflict           = errors.New("conflict")
+	ErrGatewayUnavailable = errors.New("gateway unavailable")
+	ErrProposalExpired    = errors.New("proposal expired")
+	ErrIntegrity          = errors.New("integrity check failed")
+)
+
+// IntegrityError is returned when the content a gateway returned does not match the cid it is expected to have,
+// usually the cid the chain recorded for the commit. errors.Is reports true for ErrIntegrity and types.ErrInvalidCid.
+type IntegrityError struct {
+	DataId   string
+	CommitId string
+	// Expected is the cid the content should have, Actual the cid of the content returned.
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%s: content of %s commit %s has cid %s, expected %s", ErrIntegrity, e.DataId, e.CommitId, e.Actual, e.Expected)
+}
+
+func (e *IntegrityError) Is(target error) bool {
+	return target == ErrIntegrity || target == types.ErrInvalidCid
+}
+
+// Error is returned for the failures reported by the gateway or the chain. It unwraps to the original error,
+// errors.Is reports true for its Kind and for the sao-node or sao chain error identified by Codespace and Code.
+type Error struct {
+	// Op is the gateway or chain call which failed.
+	Op string
+	// Kind is one of the sentinel errors, nil if the failure is not classified.
+	Kind error
+	// Codespace and Code identify the registered sao-node or sao chain error at the root of the failure, they are
+	// empty if unknown.
+	Codespace string
+	Code      uint32
+	Err       error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	if e.Kind != nil && target == e.Kind {
+		return true
+	}
+	var registered *sdkerrors.Error
+	if e.Code != 0 && errors.As(target, &registered) {
+		return registered.Codespace() == e.Codespace && registered.ABCICode() == e.Code
+	}
+	return false
+}
+
+// errorKinds maps the registered errors of sao-node and the sao chain onto the sentinel errors.
+var errorKinds = []struct {
+	err  *sdkerrors.Error
+	kind error
+}{
+	{types.ErrNotFound, ErrNotFound},
+	{types.ErrDataMissing, ErrNotFound},
+	{saotypes.ErrOrderNotFound, ErrNotFound},
+	{types.ErrConflictName, ErrConflict},
+	{types.ErrConflictId, ErrConflict},
+	{modeltypes.ErrDataIdExists, ErrConflict},
+	{modeltypes.ErrModelExists, ErrConflict},
+	{modeltypes.ErrorNoPermission, ErrPermissionDenied},
+	{modeltypes.ErrOnlyOwner, ErrPermissionDenied},
+	{saotypes.ErrorNoPermission, ErrPermissionDenied},
+	{types.ErrExpiredOrder, ErrProposalExpired},
+}
+
+// errorPatterns classify the errors by their message, for the failures without a known code.
+var errorPatterns = []struct {
+	pattern *regexp.Regexp
+	kind    error
+}{
+	{regexp.MustCompile(`(?i)no content updated`), ErrNoChanges},
+	{regexp.MustCompile(`invalid query, LastValidHeight:\d+ > now:\d+`), ErrProposalExpired},
+	{regexp.MustCompile(`(?i)no permission|permission denied|invalid permission|owner only|code = PermissionDenied`), ErrPermissionDenied},
+	{regexp.MustCompile(`(?i)not found|code = NotFound`), ErrNotFound},
+	{regexp.MustCompile(`(?i)already exist|exsiting already|conflict|code = AlreadyExists`), ErrConflict},
+	{regexp.MustCompile(`(?i)http status 5\d\d|connection refused|connection reset|websocket connection closed|code = Unavailable`), ErrGatewayUnavailable},
+}
+
+// errorCodePattern matches the code sao-node appends to its error messages, see types.Wrap and types.Wrapf.
+var errorCodePattern = regexp.MustCompile(`(\w+) error: code: (?:Code)?\((\d+)\)`)
+
+// classifyError wraps a gateway or chain error into an *Error, context errors are returned as they are.
+func classifyError(op string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	e = &Error{
+		Op:  op,
+		Err: err,
+	}
+
+	// errors which crossed the JSON-RPC boundary only carry their codes in the message. types.Wrap renders
+	// the cause before the error wrapping it, so the first code is the one of the root cause.
+	match := errorCodePattern.FindStringSubmatch(err.Error())
+	if match != nil {
+		code, parseErr := strconv.ParseUint(match[2], 10, 32)
+		if parseErr == nil {
+			e.Codespace, e.Code = match[1], uint32(code)
+		}
+	} else {
+		var registered *sdkerrors.Error
+		if errors.As(err, &registered) {
+			e.Codespace, e.Code = registered.Codespace(), registered.ABCICode()
+		}
+	}
+
+	if e.Code != 0 {
+		for _, k := range errorKinds {
+			if k.err.Codespace() == e.Codespace && k.err.ABCICode() == e.Code {
+				e.Kind = k.kind
+				break
+			}
+		}
+	}
+
+	if e.Kind == nil {
+		var connErr *jsonrpc.RPCConnectionError
+		var netErr net.Error
+		if errors.As(err, &connErr) || errors.As(err, &netErr) {
+			e.Kind = ErrGatewayUnavailable
+		}
+	}
+
+	if e.Kind == nil {
+		for _, p := range errorPatterns {
+			if p.pattern.MatchString(err.Error()) {
+				e.Kind = p.kind
+				break
+			}
+		}
+	}
+	return e
+}
+
+// errorGateway classifies the errors returned by the gateway.
+type errorGateway struct {
+	GatewayApi
+}
+
+func (g *errorGateway) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	resp, err := g.GatewayApi.ModelCreate(ctx, req, orderProposal, orderId, content)
+	return resp, classifyError("ModelCreate", err)
+}
+
+func (g *errorGateway) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) {
+	resp, err := g.GatewayApi.ModelCreateFile(ctx, req, orderProposal, orderId)
+	return resp, classifyError("ModelCreateFile", err)
+}
+
+func (g *errorGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	resp, err := g.GatewayApi.ModelLoad(ctx, req)
+	return resp, classifyError("ModelLoad", err)
+}
+
+func (g *errorGateway) ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error) {
+	resp, err := g.GatewayApi.ModelDelete(ctx, req, isPublish)
+	return resp, classifyError("ModelDelete", err)
+}
+
+func (g *errorGateway) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
+	resp, err := g.GatewayApi.ModelShowCommits(ctx, req)
+	return resp, classifyError("ModelShowCommits", err)
+}
+
+func (g *errorGateway) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	resp, err := g.GatewayApi.ModelUpdate(ctx, req, orderProposal, orderId, patch)
+	return resp, classifyError("ModelUpdate", err)
+}
+
+func (g *errorGateway) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) {
+	resp, err := g.GatewayApi.ModelRenewOrder(ctx, req, isPublish)
+	return resp, classifyError("ModelRenewOrder", err)
+}
+
+func (g *errorGateway) ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error) {
+	resp, err := g.GatewayApi.ModelUpdatePermission(ctx, req, isPublish)
+	return resp, classifyError("ModelUpdatePermission", err)
+}
+
+func (g *errorGateway) GetNodeAddress(ctx context.Context) (string, error) {
+	address, err := g.GatewayApi.GetNodeAddress(ctx)
+	return address, classifyError("GetNodeAddress", err)
+}
+
+// errorChain classifies the errors returned by the chain.
+type errorChain struct {
+	ChainApi
+}
+
+func (c *errorChain) GetLastHeight(ctx context.Context) (int64, error) {
+	height, err := c.ChainApi.GetLastHeight(ctx)
+	return height, classifyError("GetLastHeight", err)
+}
+
+func (c *errorChain) GetNodePeer(ctx context.Context, creator string) (string, error) {
+	peerInfo, err := c.ChainApi.GetNodePeer(ctx, creator)
+	return peerInfo, classifyError("GetNodePeer", err)
+}
+
+func (c *errorChain) QueryDidParams(ctx context.Context) (string, error) {
+	dids, err := c.ChainApi.QueryDidParams(ctx)
+	return dids, classifyError("QueryDidParams", err)
+}
+
+func (c *errorChain) QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error) {
+	resp, err := c.ChainApi.QueryMetadata(ctx, req, height)
+	return resp, classifyError("QueryMetadata", err)
+}
+
+func (c *errorChain) GetModel(ctx context.Context, key string) (*modeltypes.QueryGetModelResponse, error) {
+	resp, err := c.ChainApi.GetModel(ctx, key)
+	return resp, classifyError("GetModel", err)
+}
+
+func (c *errorChain) GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error) {
+	resp, err := c.ChainApi.GetMeta(ctx, dataId)
+	return resp, classifyError("GetMeta", err)
+}
+
+func (c *errorChain) ListMetaByDid(ctx context.Context, did string) ([]modeltypes.Metadata, error) {
+	resp, err := c.ChainApi.ListMetaByDid(ctx, did)
+	return resp, classifyError("ListMetaByDid", err)
+}
+
+func (c *errorChain) GetBlock(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	resp, err := c.ChainApi.GetBlock(ctx, height)
+	return resp, classifyError("GetBlock", err)
+}
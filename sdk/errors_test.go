@@ -0,0 +1,74 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// remote returns err as it is received from the gateway over JSON-RPC, with its message only.
+func remote(err error) error {
+	return errors.New(err.Error())
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		kind      error
+		codespace string
+		code      uint32
+	}{
+		{"wrapped", types.Wrapf(types.ErrNotFound, "dataId:%s not found", "1b0c"), ErrNotFound, "model", 14005},
+		{"remote", remote(types.Wrapf(types.ErrConflictName, "alias m")), ErrConflict, "model", 14004},
+		{"root cause of a wrap", remote(types.Wrap(types.ErrQueryMetadataFailed, types.Wrapf(types.ErrExpiredOrder, "order 3"))), ErrProposalExpired, "model", 14029},
+		{"wrap without cause", remote(types.Wrap(types.ErrQueryMetadataFailed, nil)), nil, "chain", 11015},
+		{"chain error", sdkerrors.Wrap(modeltypes.ErrorNoPermission, "1b0c"), ErrPermissionDenied, modeltypes.ModuleName, 4106},
+		{"unavailable", errors.New("RPC client error: sendRequest failed: http status 502 Bad Gateway"), ErrGatewayUnavailable, "", 0},
+		{"expired query", errors.New("invalid query, LastValidHeight:12 > now:30"), ErrProposalExpired, "", 0},
+		{"field name only", errors.New(`Value in field "LastValidHeight" was too long`), nil, "", 0},
+		{"message only", fmt.Errorf("rpc error: code = NotFound desc = model 1b0c"), ErrNotFound, "", 0},
+		{"unknown", errors.New("something else"), nil, "", 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := classifyError("ModelLoad", c.err)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("got %T", err)
+			}
+			if e.Kind != c.kind || e.Codespace != c.codespace || e.Code != c.code {
+				t.Fatalf("got %v, %s %d, want %v, %s %d", e.Kind, e.Codespace, e.Code, c.kind, c.codespace, c.code)
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("%v does not unwrap to %v", err, c.err)
+			}
+			if c.kind != nil && !errors.Is(err, c.kind) {
+				t.Fatalf("%v is not %v", err, c.kind)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorIsRegistered(t *testing.T) {
+	err := classifyError("ModelLoad", remote(types.Wrap(types.ErrQueryMetadataFailed, types.Wrapf(types.ErrNotFound, "dataId:1b0c not found"))))
+	if !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("%v is not %v", err, types.ErrNotFound)
+	}
+	if errors.Is(err, types.ErrQueryMetadataFailed) {
+		t.Fatalf("%v is %v", err, types.ErrQueryMetadataFailed)
+	}
+}
+
+func TestClassifyErrorPassesThrough(t *testing.T) {
+	for _, err := range []error{nil, context.Canceled, context.DeadlineExceeded, &Error{Op: "ModelLoad", Err: errors.New("x")}} {
+		if got := classifyError("ModelLoad", err); got != err {
+			t.Errorf("got %v, want %v", got, err)
+		}
+	}
+}
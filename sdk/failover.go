@@ -0,0 +1,270 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// DefaultHealthCheckInterval is how long an unavailable gateway is skipped before it is checked again.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+type poolGateway struct {
+	api GatewayApi
+	// address is the chain address reported by the gateway, empty until it passed a health check.
+	address string
+	// down is when the gateway was found unavailable, zero if it is healthy.
+	down time.Time
+}
+
+// GatewayPool is a GatewayApi spreading the calls over several gateways. It sticks to one healthy gateway
+// and fails over to the next one when it becomes unavailable. Unavailable gateways are health checked with
+// GetNodeAddress again after the health check interval.
+type GatewayPool struct {
+	interval time.Duration
+	log      Logger
+
+	mu       sync.Mutex
+	gateways []*poolGateway
+	current  int
+}
+
+var _ GatewayApi = (*GatewayPool)(nil)
+
+// NewGatewayPool creates a pool over the given gateways, the first one is preferred.
+// A zero interval means DefaultHealthCheckInterval.
+func NewGatewayPool(gateways []GatewayApi, interval time.Duration, log Logger) *GatewayPool {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	if log == nil {
+		log = DefaultConfig().Logger
+	}
+
+	pool := &GatewayPool{
+		interval: interval,
+		log:      log,
+	}
+	for _, gateway := range gateways {
+		pool.gateways = append(pool.gateways, &poolGateway{
+			api: gateway,
+		})
+	}
+	return pool
+}
+
+// withTimeout returns a pool over the same gateways, whose calls are each bounded by timeout.
+func (p *GatewayPool) withTimeout(timeout time.Duration) *GatewayPool {
+	gateways := make([]GatewayApi, 0, len(p.gateways))
+	for _, gateway := range p.gateways {
+		gateways = append(gateways, &timeoutGateway{GatewayApi: gateway.api, timeout: timeout})
+	}
+	return NewGatewayPool(gateways, p.interval, p.log)
+}
+
+// Len returns the number of gateways in the pool.
+func (p *GatewayPool) Len() int {
+	return len(p.gateways)
+}
+
+// pin selects a healthy gateway and returns it with its address. Gateways which are down are only checked once
+// their health check interval passed, unless no other gateway is left.
+func (p *GatewayPool) pin(ctx context.Context) (GatewayApi, string, error) {
+	var lastErr error
+	for _, recheckAll := range []bool{false, true} {
+		for _, gateway := range p.candidates(recheckAll) {
+			address, err := p.check(ctx, gateway)
+			if err == nil {
+				return gateway.api, address, nil
+			}
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
+			}
+			lastErr = err
+		}
+	}
+	if lastErr == nil {
+		lastErr = types.Wrapf(types.ErrInvalidGateway, "no gateway configured")
+	}
+	return nil, "", lastErr
+}
+
+// candidates lists the gateways to try in order, starting at the current one.
+func (p *GatewayPool) candidates(recheckAll bool) []*poolGateway {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var gateways []*poolGateway
+	for i := range p.gateways {
+		gateway := p.gateways[(p.current+i)%len(p.gateways)]
+		if recheckAll || gateway.down.IsZero() || time.Since(gateway.down) >= p.interval {
+			gateways = append(gateways, gateway)
+		}
+	}
+	return gateways
+}
+
+// check returns the address of a healthy gateway, or health checks it if it was down or never used.
+func (p *GatewayPool) check(ctx context.Context, gateway *poolGateway) (string, error) {
+	p.mu.Lock()
+	if gateway.down.IsZero() && gateway.address != "" {
+		p.use(gateway)
+		p.mu.Unlock()
+		return gateway.address, nil
+	}
+	p.mu.Unlock()
+
+	address, err := gateway.api.GetNodeAddress(ctx)
+	err = classifyError("GetNodeAddress", err)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		if gateway.down.IsZero() {
+			p.log.Warnf("gateway %d is unavailable: %v", p.index(gateway), err)
+		}
+		gateway.down = time.Now()
+		return "", err
+	}
+	if !gateway.down.IsZero() {
+		p.log.Infof("gateway %d %s is available again", p.index(gateway), address)
+	}
+	gateway.address = address
+	gateway.down = time.Time{}
+	p.use(gateway)
+	return address, nil
+}
+
+// use makes gateway the current one, the caller must hold the lock.
+func (p *GatewayPool) use(gateway *poolGateway) {
+	index := p.index(gateway)
+	if index != p.current {
+		p.log.Infof("switch to gateway %d %s", index, gateway.address)
+		p.current = index
+	}
+}
+
+func (p *GatewayPool) index(gateway *poolGateway) int {
+	for i, g := range p.gateways {
+		if g == gateway {
+			return i
+		}
+	}
+	return -1
+}
+
+// report marks the gateway down if err shows it is unavailable, and tells whether the call can be sent to
+// another gateway: idempotent calls always can, the others only if the request was not delivered.
+func (p *GatewayPool) report(api GatewayApi, err error, idempotent bool) bool {
+	if !errors.Is(err, ErrGatewayUnavailable) {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, gateway := range p.gateways {
+		if gateway.api == api && gateway.down.IsZero() {
+			p.log.Warnf("gateway %d %s is unavailable: %v", p.index(gateway), gateway.address, err)
+			gateway.down = time.Now()
+		}
+	}
+	return len(p.gateways) > 1 && (idempotent || notDelivered(err))
+}
+
+// notDelivered tells whether err shows the request could not even be sent.
+func notDelivered(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// call runs call on a healthy gateway, failing over to the other gateways as report allows.
+func (p *GatewayPool) call(ctx context.Context, idempotent bool, call func(api GatewayApi) error) error {
+	for attempt := 1; ; attempt++ {
+		api, _, err := p.pin(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = call(api)
+		if err == nil || attempt >= len(p.gateways) || !p.report(api, err, idempotent) {
+			return err
+		}
+	}
+}
+
+func (p *GatewayPool) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (resp apitypes.CreateResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelCreate(ctx, req, orderProposal, orderId, content)
+		return classifyError("ModelCreate", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (resp apitypes.CreateResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelCreateFile(ctx, req, orderProposal, orderId)
+		return classifyError("ModelCreateFile", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelLoad(ctx context.Context, req *types.MetadataProposal) (resp apitypes.LoadResp, err error) {
+	err = p.call(ctx, true, func(api GatewayApi) error {
+		resp, err = api.ModelLoad(ctx, req)
+		return classifyError("ModelLoad", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (resp apitypes.DeleteResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelDelete(ctx, req, isPublish)
+		return classifyError("ModelDelete", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (resp apitypes.ShowCommitsResp, err error) {
+	err = p.call(ctx, true, func(api GatewayApi) error {
+		resp, err = api.ModelShowCommits(ctx, req)
+		return classifyError("ModelShowCommits", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (resp apitypes.UpdateResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelUpdate(ctx, req, orderProposal, orderId, patch)
+		return classifyError("ModelUpdate", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (resp apitypes.RenewResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelRenewOrder(ctx, req, isPublish)
+		return classifyError("ModelRenewOrder", err)
+	})
+	return resp, err
+}
+
+func (p *GatewayPool) ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (resp apitypes.UpdatePermissionResp, err error) {
+	err = p.call(ctx, false, func(api GatewayApi) error {
+		resp, err = api.ModelUpdatePermission(ctx, req, isPublish)
+		return classifyError("ModelUpdatePermission", err)
+	})
+	return resp, err
+}
+
+// GetNodeAddress returns the address of the gateway the next calls go to.
+func (p *GatewayPool) GetNodeAddress(ctx context.Context) (string, error) {
+	_, address, err := p.pin(ctx)
+	return address, err
+}
@@ -0,0 +1,51 @@
+package sdk_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+)
+
+func TestGatewayPoolFailover(t *testing.T) {
+	ctx := context.Background()
+	primary := sdktest.NewServer()
+	defer primary.Close()
+	secondary := sdktest.NewServerWithStore(primary.Store, "sao1fakegateway2", "/ip4/127.0.0.1/tcp/5163/p2p/12D3KooWFakeGateway2")
+	defer secondary.Close()
+
+	var gateways []sdk.GatewayApi
+	for _, srv := range []*sdktest.Server{primary, secondary} {
+		gateway, closer, err := sdk.NewNodeApi(ctx, srv.URL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closer()
+		gateways = append(gateways, gateway)
+	}
+	pool := sdk.NewGatewayPool(gateways, time.Hour, nil)
+	home := t.TempDir()
+	if _, err := sdktest.CreateAccount(ctx, home, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	client := sdk.NewSaoClientApiWithBackends(pool, primary.Chain, "alice", home)
+	dataId := createModel(t, client, `{"a":1}`, "m")
+
+	primary.Close()
+	if got := load(t, client, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+	address, err := pool.GetNodeAddress(ctx)
+	if err != nil || address != "sao1fakegateway2" {
+		t.Fatalf("got %q, %v, want the secondary gateway", address, err)
+	}
+	createModel(t, client, `{"b":1}`, "m2")
+
+	// a create which may have reached the gateway is not sent to another one
+	secondary.FailRequests(1)
+	if _, _, err := client.CreateModel(ctx, `{"c":1}`, "g", 1, 100, "m3", 1, false); err == nil {
+		t.Fatal("created a model through a failing gateway")
+	}
+}
@@ -0,0 +1,29 @@
+package sdk
+
+import "context"
+
+type keyNameKey struct{}
+
+// WithKeyName returns a context selecting the key the client acts as in the calls made with it, instead of
+// the key of the client. It lets a single client serve many identities, see also SaoClientApi.As.
+func WithKeyName(ctx context.Context, keyName string) context.Context {
+	return context.WithValue(ctx, keyNameKey{}, keyName)
+}
+
+// KeyName returns the key the client acts as in the calls made with ctx: the key selected with WithKeyName,
+// or the key of the client.
+func (sc *SaoClientApi) KeyName(ctx context.Context) string {
+	if keyName, ok := ctx.Value(keyNameKey{}).(string); ok && keyName != "" {
+		return keyName
+	}
+	return sc.keyName
+}
+
+// As returns a view of the client acting as keyName. The view shares the connections, the cached did
+// managers and the caches of the client, so closing either closes both. A key selected with WithKeyName
+// still takes precedence.
+func (sc *SaoClientApi) As(keyName string) *SaoClientApi {
+	view := *sc
+	view.keyName = keyName
+	return &view
+}
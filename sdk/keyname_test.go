@@ -0,0 +1,90 @@
+package sdk_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+)
+
+func TestKeyName(t *testing.T) {
+	ctx := context.Background()
+	client := sdk.NewSaoClientApiWithBackends(nil, nil, "alice", t.TempDir())
+	bob := client.As("bob")
+
+	cases := []struct {
+		name   string
+		client *sdk.SaoClientApi
+		ctx    context.Context
+		want   string
+	}{
+		{"client", client, ctx, "alice"},
+		{"context", client, sdk.WithKeyName(ctx, "bob"), "bob"},
+		{"empty context", client, sdk.WithKeyName(ctx, ""), "alice"},
+		{"view", bob, ctx, "bob"},
+		{"context over view", bob, sdk.WithKeyName(ctx, "carol"), "carol"},
+	}
+	for _, c := range cases {
+		if got := c.client.KeyName(c.ctx); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+	if got := client.KeyName(ctx); got != "alice" {
+		t.Fatalf("the view changed the client key to %s", got)
+	}
+}
+
+func TestIdentitiesOnOneClient(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+	if _, err := sdktest.CreateAccount(ctx, home, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	// each key has its own cached did manager, shared with the views
+	aliceDid, bobDid := didOf(t, client, "alice"), didOf(t, client, "bob")
+	if aliceDid == bobDid {
+		t.Fatalf("alice and bob share the did %s", aliceDid)
+	}
+	bobManager, _, err := client.GetDidManager(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewManager, _, err := client.As("bob").GetDidManager(ctx, "bob")
+	if err != nil || viewManager != bobManager {
+		t.Fatalf("the view derived its own did manager, %v", err)
+	}
+
+	// a model created as bob is owned by bob, and only readable as bob
+	asBob := sdk.WithKeyName(ctx, "bob")
+	_, dataId, err := client.CreateModel(asBob, `{"a":1}`, "g", 1, 100, "m", 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := srv.Chain.GetMeta(ctx, dataId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Metadata.Owner != bobDid {
+		t.Fatalf("got owner %s, want %s", meta.Metadata.Owner, bobDid)
+	}
+	if _, err := client.Load(ctx, dataId, "", "", "g"); err == nil {
+		t.Fatal("alice loaded bob's model")
+	}
+	for name, loadAs := range map[string]func() ([]byte, error){
+		"context":           func() ([]byte, error) { return client.Load(asBob, dataId, "", "", "g") },
+		"view":              func() ([]byte, error) { return client.As("bob").Load(ctx, dataId, "", "", "g") },
+		"context over view": func() ([]byte, error) { return client.As("alice").Load(asBob, dataId, "", "", "g") },
+	} {
+		if got, err := loadAs(); err != nil || string(got) != `{"a":1}` {
+			t.Fatalf("%s: got %s, %v", name, got, err)
+		}
+	}
+	if _, err := client.As("bob").Load(sdk.WithKeyName(ctx, "alice"), dataId, "", "", "g"); err == nil {
+		t.Fatal("alice loaded bob's model through bob's view")
+	}
+}
@@ -0,0 +1,448 @@
+package sdk
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// maxLoadField is the largest string, in bytes, of a load response but its content.
+const maxLoadField = 64 << 10
+
+// decodeLoadStream decodes the response to a Sao.ModelLoad rpc request as it arrives. r is a JSON encoded
+// types.RpcResp, whose Data is the JSON encoded apitypes.LoadResp. It returns the response without its Content,
+// and the content, which is decoded from r as it is read and fails if the rest of the response is invalid.
+// The response is nil if the gateway loaded nothing, the sao-node gateways do not report the errors of the
+// loads over their transport.
+func decodeLoadStream(r io.Reader) (*apitypes.LoadResp, io.Reader, error) {
+	outer := bufio.NewReader(r)
+	err := expectByte(outer, '{')
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rpcErr string
+	for prev := byte('{'); ; prev = ',' {
+		end, err := nextMember(outer, prev)
+		if err != nil {
+			return nil, nil, err
+		}
+		if end {
+			break
+		}
+
+		key, err := readMemberKey(outer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key != "Data" {
+			value, err := readString(outer)
+			if err != nil {
+				return nil, nil, err
+			}
+			if key == "Error" {
+				rpcErr = value
+			}
+			continue
+		}
+
+		err = expectByte(outer, '"')
+		if err != nil {
+			return nil, nil, err
+		}
+		s := &loadStream{
+			outer: outer,
+			inner: bufio.NewReader(&jsonStringReader{r: outer}),
+		}
+		resp, err := s.decodeHead()
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp != nil {
+			return resp, s, nil
+		}
+		return nil, nil, s.finishOuter()
+	}
+
+	if rpcErr != "" {
+		return nil, nil, types.Wrapf(types.ErrFailuresResponsed, "%s", rpcErr)
+	}
+	return nil, nil, nil
+}
+
+// loadStream reads the content of a load response, Data being inner within outer.
+type loadStream struct {
+	outer   *bufio.Reader
+	inner   *bufio.Reader
+	content io.Reader
+	// closed tells whether the load response ended before the content.
+	closed bool
+	err    error
+}
+
+// decodeHead decodes the members of the load response up to its content, it returns a nil response if Data is
+// empty.
+func (s *loadStream) decodeHead() (*apitypes.LoadResp, error) {
+	_, err := s.inner.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	err = expectByte(s.inner, '{')
+	if err != nil {
+		return nil, err
+	}
+
+	var resp apitypes.LoadResp
+	for prev := byte('{'); ; prev = ',' {
+		end, err := nextMember(s.inner, prev)
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			s.content, s.closed = eofReader{}, true
+			return &resp, nil
+		}
+
+		key, err := readMemberKey(s.inner)
+		if err != nil {
+			return nil, err
+		}
+		if key == "Content" {
+			s.content, err = s.contentReader()
+			if err != nil {
+				return nil, err
+			}
+			return &resp, nil
+		}
+		err = s.decodeMember(&resp, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *loadStream) decodeMember(resp *apitypes.LoadResp, key string) error {
+	value, err := readString(s.inner)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "DataId":
+		resp.DataId = value
+	case "Alias":
+		resp.Alias = value
+	case "CommitId":
+		resp.CommitId = value
+	case "Version":
+		resp.Version = value
+	case "Cid":
+		resp.Cid = value
+	}
+	return nil
+}
+
+// contentReader returns the reader of the base64 encoded content, or of no content if it is null.
+func (s *loadStream) contentReader() (io.Reader, error) {
+	skipSpace(s.inner)
+	b, err := s.inner.ReadByte()
+	if err != nil {
+		return nil, invalidLoadStream(err)
+	}
+	switch b {
+	case '"':
+		return base64.NewDecoder(base64.StdEncoding, &jsonStringReader{r: s.inner}), nil
+	case 'n':
+		for _, want := range []byte("ull") {
+			err = expectByte(s.inner, want)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return eofReader{}, nil
+	default:
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: unexpected %q in Content", b)
+	}
+}
+
+func (s *loadStream) Read(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := s.content.Read(p)
+	if err == io.EOF {
+		err = s.finish()
+		if err == nil {
+			err = io.EOF
+		}
+	} else if err != nil {
+		err = invalidLoadStream(err)
+	}
+	if err != nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// finish checks the rest of the response once the content is read.
+func (s *loadStream) finish() error {
+	for !s.closed {
+		end, err := nextMember(s.inner, ',')
+		if err != nil {
+			return err
+		}
+		if end {
+			break
+		}
+		_, err = readMemberKey(s.inner)
+		if err != nil {
+			return err
+		}
+		_, err = readString(s.inner)
+		if err != nil {
+			return err
+		}
+	}
+	skipSpace(s.inner)
+	_, err := s.inner.ReadByte()
+	if err == nil {
+		return types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: trailing data")
+	}
+	if err != io.EOF {
+		return err
+	}
+	return s.finishOuter()
+}
+
+// finishOuter checks the members of the rpc response after Data.
+func (s *loadStream) finishOuter() error {
+	var rpcErr string
+	for {
+		end, err := nextMember(s.outer, ',')
+		if err != nil {
+			return err
+		}
+		if end {
+			break
+		}
+		key, err := readMemberKey(s.outer)
+		if err != nil {
+			return err
+		}
+		value, err := readString(s.outer)
+		if err != nil {
+			return err
+		}
+		if key == "Error" {
+			rpcErr = value
+		}
+	}
+	if rpcErr != "" {
+		return types.Wrapf(types.ErrFailuresResponsed, "%s", rpcErr)
+	}
+	return nil
+}
+
+// nextMember moves to the next member of an object, after prev, '{' for the first member or ',' for the next
+// ones. It reports whether the object ended instead.
+func nextMember(r *bufio.Reader, prev byte) (bool, error) {
+	skipSpace(r)
+	b, err := r.ReadByte()
+	if err != nil {
+		return false, invalidLoadStream(err)
+	}
+	if b == '}' {
+		return true, nil
+	}
+	if prev == '{' {
+		return false, r.UnreadByte()
+	}
+	if b != ',' {
+		return false, types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: unexpected %q", b)
+	}
+	return false, nil
+}
+
+// readMemberKey reads the key of a member and the colon following it.
+func readMemberKey(r *bufio.Reader) (string, error) {
+	key, err := readString(r)
+	if err != nil {
+		return "", err
+	}
+	return key, expectByte(r, ':')
+}
+
+// readString reads a whole JSON string.
+func readString(r *bufio.Reader) (string, error) {
+	err := expectByte(r, '"')
+	if err != nil {
+		return "", err
+	}
+	value, err := io.ReadAll(io.LimitReader(&jsonStringReader{r: r}, maxLoadField+1))
+	if err != nil {
+		return "", invalidLoadStream(err)
+	}
+	if len(value) > maxLoadField {
+		return "", types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: string longer than %d bytes", maxLoadField)
+	}
+	return string(value), nil
+}
+
+// expectByte skips the white spaces and reads want.
+func expectByte(r *bufio.Reader, want byte) error {
+	skipSpace(r)
+	b, err := r.ReadByte()
+	if err != nil {
+		return invalidLoadStream(err)
+	}
+	if b != want {
+		return types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: unexpected %q, expected %q", b, want)
+	}
+	return nil
+}
+
+func skipSpace(r *bufio.Reader) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			_ = r.UnreadByte()
+			return
+		}
+	}
+}
+
+// invalidLoadStream turns the end of a load response in the middle of a value into an error, other errors are
+// the ones of the transport.
+func invalidLoadStream(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: unexpected end")
+	}
+	if _, ok := err.(base64.CorruptInputError); ok {
+		return types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: %v", err)
+	}
+	return err
+}
+
+// jsonStringReader reads the unescaped value of a JSON string, from after its opening quote up to its closing
+// quote.
+type jsonStringReader struct {
+	r       *bufio.Reader
+	pending []byte
+	done    bool
+	err     error
+}
+
+func (s *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.pending) > 0 {
+			c := copy(p[n:], s.pending)
+			s.pending = s.pending[c:]
+			n += c
+			continue
+		}
+		if s.done || s.err != nil {
+			break
+		}
+
+		b, err := s.r.ReadByte()
+		switch {
+		case err != nil:
+			s.err = invalidLoadStream(err)
+		case b == '"':
+			s.done = true
+		case b == '\\':
+			s.pending, s.err = s.unescape()
+		case b < 0x20:
+			s.err = types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: control character %q in string", b)
+		default:
+			p[n] = b
+			n++
+		}
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.done {
+		return 0, io.EOF
+	}
+	return 0, nil
+}
+
+// unescape decodes the escape sequence after a backslash.
+func (s *jsonStringReader) unescape() ([]byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return nil, invalidLoadStream(err)
+	}
+	switch b {
+	case '"', '\\', '/':
+		return []byte{b}, nil
+	case 'b':
+		return []byte{'\b'}, nil
+	case 'f':
+		return []byte{'\f'}, nil
+	case 'n':
+		return []byte{'\n'}, nil
+	case 'r':
+		return []byte{'\r'}, nil
+	case 't':
+		return []byte{'\t'}, nil
+	case 'u':
+		r, err := s.readHex()
+		if err != nil {
+			return nil, err
+		}
+		if utf16.IsSurrogate(r) {
+			// the low surrogate must follow, as encoding/json does a lone surrogate decodes to U+FFFD
+			next, err := s.r.Peek(2)
+			if err == nil && next[0] == '\\' && next[1] == 'u' {
+				_, _ = s.r.Discard(2)
+				low, err := s.readHex()
+				if err != nil {
+					return nil, err
+				}
+				if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+					return utf8.AppendRune(nil, pair), nil
+				}
+				return utf8.AppendRune(utf8.AppendRune(nil, utf8.RuneError), low), nil
+			}
+			r = utf8.RuneError
+		}
+		return utf8.AppendRune(nil, r), nil
+	default:
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: invalid escape %q", b)
+	}
+}
+
+func (s *jsonStringReader) readHex() (rune, error) {
+	var hex [4]byte
+	_, err := io.ReadFull(s.r, hex[:])
+	if err != nil {
+		return 0, invalidLoadStream(err)
+	}
+	r, err := strconv.ParseUint(string(hex[:]), 16, 16)
+	if err != nil {
+		return 0, types.Wrapf(types.ErrUnMarshalFailed, "invalid load response: invalid escape \\u%s", hex[:])
+	}
+	return rune(r), nil
+}
+
+// eofReader is an empty content.
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
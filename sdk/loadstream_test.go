@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func rpcLoadResp(t *testing.T, resp apitypes.LoadResp, rpcErr string) string {
+	t.Helper()
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := json.Marshal(types.RpcResp{Data: string(data), Error: rpcErr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+func TestDecodeLoadStream(t *testing.T) {
+	binary := make([]byte, 1<<20)
+	if _, err := rand.Read(binary); err != nil {
+		t.Fatal(err)
+	}
+	head := apitypes.LoadResp{DataId: "d", Alias: "a \"quoted\" é", CommitId: "c", Version: "v1", Cid: "cid"}
+	withContent := func(content []byte) apitypes.LoadResp {
+		resp := head
+		resp.Content = content
+		return resp
+	}
+
+	cases := []struct {
+		name    string
+		stream  string
+		content []byte
+	}{
+		{"json", rpcLoadResp(t, withContent([]byte(`{"a":"<b>&"}`)), ""), []byte(`{"a":"<b>&"}`)},
+		{"binary", rpcLoadResp(t, withContent(binary), ""), binary},
+		{"null content", rpcLoadResp(t, head, ""), nil},
+		{"escapes", `{ "Error" : "", "Data" : "{\"DataId\":\"d\",\"Alias\":\"a \\\"quoted\\\" é\",` +
+			`\"CommitId\":\"c\",\"Version\":\"v1\",\"Cid\":\"cid\",\"Content\":\"e30=\"}" }`, []byte("{}")},
+		{"unicode escapes", `{"Data":"{\"DataId\":\"d\",\"Alias\":\"a \\\"quoted\\\" \u00e9\",\"CommitId\":\"c\",` +
+			`\"Version\":\"v1\",\"Cid\":\"cid\",\"Content\":\"8J+Ygg==\",\"Extra\":\"\ud83d\ude00\"}"}`, []byte("😂")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, content, err := decodeLoadStream(iotest.OneByteReader(strings.NewReader(c.stream)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*resp, head) {
+				t.Fatalf("got %+v, want %+v", resp, head)
+			}
+			got, err := io.ReadAll(content)
+			if err != nil || !bytes.Equal(got, c.content) {
+				t.Fatalf("got %d bytes, %v, want %d bytes", len(got), err, len(c.content))
+			}
+		})
+	}
+}
+
+func TestDecodeLoadStreamFailures(t *testing.T) {
+	valid := rpcLoadResp(t, apitypes.LoadResp{DataId: "d", Content: []byte("content")}, "")
+
+	// nothing loaded
+	resp, _, err := decodeLoadStream(strings.NewReader(`{"Data":"","Error":""}`))
+	if resp != nil || err != nil {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+	resp, _, err = decodeLoadStream(strings.NewReader(`{"Data":"","Error":"N/a"}`))
+	if resp != nil || !errors.Is(err, types.ErrFailuresResponsed) {
+		t.Fatalf("got %+v, %v, want %v", resp, err, types.ErrFailuresResponsed)
+	}
+
+	cases := []struct {
+		name   string
+		stream string
+		err    error
+	}{
+		{"truncated content", valid[:len(valid)-10], types.ErrUnMarshalFailed},
+		{"truncated response", valid[:len(valid)-1], types.ErrUnMarshalFailed},
+		{"trailing data", strings.Replace(valid, `}","Error"`, `}x","Error"`, 1), types.ErrUnMarshalFailed},
+		{"corrupt content", strings.Replace(valid, "Y29udGVudA==", "Y29ud!VudA==", 1), types.ErrUnMarshalFailed},
+		{"error after the content", strings.Replace(valid, `"Error":""`, `"Error":"failed"`, 1), types.ErrFailuresResponsed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, content, err := decodeLoadStream(strings.NewReader(c.stream))
+			if err == nil {
+				if resp.DataId != "d" {
+					t.Fatalf("got %+v", resp)
+				}
+				_, err = io.ReadAll(content)
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+		})
+	}
+
+	for _, stream := range []string{"", "[]", `{"Data":1}`, `{"Data":"[]"}`, `{"Data":"{\"Content\":1}"}`} {
+		if _, _, err := decodeLoadStream(strings.NewReader(stream)); !errors.Is(err, types.ErrUnMarshalFailed) {
+			t.Fatalf("%s: got %v, want %v", stream, err, types.ErrUnMarshalFailed)
+		}
+	}
+}
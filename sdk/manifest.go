@@ -0,0 +1,248 @@
+package sdk
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// UploadState is the upload state of a file in an UploadManifest.
+type UploadState string
+
+const (
+	// UploadPending means the file was not uploaded yet, or changed since it was.
+	UploadPending UploadState = "pending"
+	// UploadFailed means the last upload of the file failed, it is retried on the next run.
+	UploadFailed UploadState = "failed"
+	// UploadConfirmed means the gateway acknowledged the whole content of the file.
+	UploadConfirmed UploadState = "confirmed"
+)
+
+// manifestSaveEvery and manifestSaveInterval bound how many uploads, and for how long, are recorded in memory
+// only: a manifest is saved after manifestSaveEvery uploads or manifestSaveInterval, whichever comes first.
+const (
+	manifestSaveEvery    = 64
+	manifestSaveInterval = 2 * time.Second
+)
+
+// UploadManifestEntry records the upload of a single file.
+type UploadManifestEntry struct {
+	// Path is the path of the file relative to the manifest root, with forward slashes.
+	Path string `json:"path"`
+	// Size is the size of the file, its content is uploaded again if it changes.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Cid     string    `json:"cid,omitempty"`
+	// ContentSize is the size of the uploaded content, the size of the envelope of an encrypted file.
+	ContentSize int64       `json:"contentSize,omitempty"`
+	State       UploadState `json:"state"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// UploadManifest records the state of a directory upload in a local file, so that an interrupted upload
+// can be resumed, see UploadOptions.Manifest. Files are uploaded again if their size or mtime changed.
+// The uploads are saved in batches, the last ones before a crash may be uploaded again.
+type UploadManifest struct {
+	// Root is the absolute path of the uploaded file or directory.
+	Root  string                `json:"root"`
+	Files []UploadManifestEntry `json:"files"`
+
+	path string
+	mu   sync.Mutex
+	// indexes maps the paths of Files to their index.
+	indexes  map[string]int
+	unsaved  int
+	lastSave time.Time
+}
+
+// LoadUploadManifest reads the manifest at path, or returns an empty one to be saved there if it does not exist.
+func LoadUploadManifest(path string) (*UploadManifest, error) {
+	m := &UploadManifest{path: path}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	err = json.Unmarshal(content, m)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "invalid upload manifest %s: %v", path, err)
+	}
+	for i, entry := range m.Files {
+		// the manifests written before ContentSize recorded the size of the plain uploads only
+		if entry.State == UploadConfirmed && entry.ContentSize == 0 {
+			m.Files[i].ContentSize = entry.Size
+		}
+	}
+	return m, nil
+}
+
+// Entry returns the entry of the file at the relative path.
+func (m *UploadManifest) Entry(path string) (UploadManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(path)
+	if i < 0 {
+		return UploadManifestEntry{}, false
+	}
+	return m.Files[i], true
+}
+
+// Confirmed returns the entries of the files uploaded successfully.
+func (m *UploadManifest) Confirmed() []UploadManifestEntry {
+	return m.entries(func(state UploadState) bool { return state == UploadConfirmed })
+}
+
+// Pending returns the entries of the files still to upload, including the failed ones.
+func (m *UploadManifest) Pending() []UploadManifestEntry {
+	return m.entries(func(state UploadState) bool { return state != UploadConfirmed })
+}
+
+func (m *UploadManifest) entries(match func(UploadState) bool) []UploadManifestEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []UploadManifestEntry
+	for _, entry := range m.Files {
+		if match(entry.State) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// CreateFileRequests returns a CreateFileRequest per confirmed file, copying template with FileName, Cid
+// and Size set from the entry. FileName is the relative path of the file.
+func (m *UploadManifest) CreateFileRequests(template CreateFileRequest) []CreateFileRequest {
+	var reqs []CreateFileRequest
+	for _, entry := range m.Confirmed() {
+		req := template
+		req.FileName = entry.Path
+		req.Cid = entry.Cid
+		req.Size = uint64(entry.ContentSize)
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// Save writes the manifest back to the file it was loaded from, through a temporary file.
+func (m *UploadManifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.save()
+}
+
+func (m *UploadManifest) save() error {
+	m.unsaved, m.lastSave = 0, time.Now()
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err != nil {
+		tmp.Close()
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return types.Wrap(types.ErrCloseFileFailed, err)
+	}
+	err = os.Rename(tmp.Name(), m.path)
+	if err != nil {
+		return types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return nil
+}
+
+func (m *UploadManifest) index(path string) int {
+	if m.indexes == nil {
+		m.indexes = make(map[string]int, len(m.Files))
+		for i, entry := range m.Files {
+			m.indexes[entry.Path] = i
+		}
+	}
+	i, found := m.indexes[path]
+	if !found {
+		return -1
+	}
+	return i
+}
+
+// prepare binds the manifest to the root directory and marks the files which changed since they were
+// uploaded as pending. It returns the files which still need to be uploaded.
+func (m *UploadManifest) prepare(root string, files []uploadFile) ([]uploadFile, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidPath, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Root == "" {
+		m.Root = abs
+	}
+	if m.Root != abs {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "upload manifest %s belongs to %s, not %s", m.path, m.Root, abs)
+	}
+
+	var pending []uploadFile
+	for _, file := range files {
+		i := m.index(file.rel)
+		if i < 0 {
+			m.Files = append(m.Files, UploadManifestEntry{Path: file.rel})
+			i = len(m.Files) - 1
+			m.indexes[file.rel] = i
+		}
+
+		entry := &m.Files[i]
+		if entry.State == UploadConfirmed && entry.Size == file.size && entry.ModTime.Equal(file.modTime) {
+			continue
+		}
+		*entry = UploadManifestEntry{
+			Path:    file.rel,
+			Size:    file.size,
+			ModTime: file.modTime,
+			State:   UploadPending,
+		}
+		pending = append(pending, file)
+	}
+	return pending, m.save()
+}
+
+// record stores the outcome of an upload, the manifest is saved once enough uploads are recorded, see Save.
+func (m *UploadManifest) record(result UploadResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(result.Path)
+	if i < 0 {
+		return nil
+	}
+	entry := &m.Files[i]
+	if result.Err != nil {
+		entry.State, entry.Error = UploadFailed, result.Err.Error()
+	} else {
+		entry.State, entry.Error, entry.Cid, entry.ContentSize = UploadConfirmed, "", result.Cid, result.Size
+	}
+
+	m.unsaved++
+	if m.unsaved < manifestSaveEvery && time.Since(m.lastSave) < manifestSaveInterval {
+		return nil
+	}
+	return m.save()
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadManifestSavesInBatches(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m, err := LoadUploadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []uploadFile
+	for i := 0; i < manifestSaveEvery+1; i++ {
+		files = append(files, uploadFile{rel: fmt.Sprintf("f%d", i), size: 3, modTime: time.Unix(0, 0)})
+	}
+	pending, err := m.prepare(dir, files)
+	if err != nil || len(pending) != len(files) {
+		t.Fatalf("got %d pending files, %v", len(pending), err)
+	}
+
+	confirmed := func() int {
+		t.Helper()
+
+		saved, err := LoadUploadManifest(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(saved.Confirmed())
+	}
+	for i, file := range files {
+		if err := m.record(UploadResult{Path: file.rel, Cid: "Qm", Size: 5}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && confirmed() != 0 {
+			t.Fatal("saved after the first upload")
+		}
+	}
+	if got := confirmed(); got != manifestSaveEvery {
+		t.Fatalf("got %d confirmed files on disk, want %d", got, manifestSaveEvery)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if got := confirmed(); got != len(files) {
+		t.Fatalf("got %d confirmed files on disk, want %d", got, len(files))
+	}
+
+	// unchanged files are skipped by their file size, not by the size of the uploaded content
+	pending, err = m.prepare(dir, files)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("got %d pending files, %v", len(pending), err)
+	}
+	if entry, _ := m.Entry("f0"); entry.Size != 3 || entry.ContentSize != 5 {
+		t.Fatalf("got %+v", entry)
+	}
+}
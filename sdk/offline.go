@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// ErrOffline is returned by the calls of a client created by NewOfflineSaoClientApi which need the gateway or the chain.
+var ErrOffline = errors.New("offline, no gateway nor chain")
+
+// NewOfflineSaoClientApi creates a SaoClientApi which never connects to a gateway or a chain, to build and sign
+// proposals with the Build*Proposal methods on a host without network access, e.g. with a MemorySigner.
+// The proposals need the parameters an online client fetched, see ProposalParams, the methods which need the
+// network fail with ErrOffline.
+func NewOfflineSaoClientApi(keyName string, keyringHome string, opts ...Option) *SaoClientApi {
+	sc := NewSaoClientApiWithBackends(offlineGateway{}, offlineChain{}, keyName, keyringHome, opts...)
+	sc.offline = true
+	return sc
+}
+
+// offlineGateway is the GatewayApi of an offline client.
+type offlineGateway struct{}
+
+func (offlineGateway) ModelCreate(context.Context, *types.MetadataProposal, *types.OrderStoreProposal, uint64, []byte) (apitypes.CreateResp, error) {
+	return apitypes.CreateResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelCreateFile(context.Context, *types.MetadataProposal, *types.OrderStoreProposal, uint64) (apitypes.CreateResp, error) {
+	return apitypes.CreateResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelLoad(context.Context, *types.MetadataProposal) (apitypes.LoadResp, error) {
+	return apitypes.LoadResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelDelete(context.Context, *types.OrderTerminateProposal, bool) (apitypes.DeleteResp, error) {
+	return apitypes.DeleteResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelShowCommits(context.Context, *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
+	return apitypes.ShowCommitsResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelUpdate(context.Context, *types.MetadataProposal, *types.OrderStoreProposal, uint64, []byte) (apitypes.UpdateResp, error) {
+	return apitypes.UpdateResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelRenewOrder(context.Context, *types.OrderRenewProposal, bool) (apitypes.RenewResp, error) {
+	return apitypes.RenewResp{}, ErrOffline
+}
+
+func (offlineGateway) ModelUpdatePermission(context.Context, *types.PermissionProposal, bool) (apitypes.UpdatePermissionResp, error) {
+	return apitypes.UpdatePermissionResp{}, ErrOffline
+}
+
+func (offlineGateway) GetNodeAddress(context.Context) (string, error) {
+	return "", ErrOffline
+}
+
+// offlineChain is the ChainApi of an offline client.
+type offlineChain struct{}
+
+func (offlineChain) GetLastHeight(context.Context) (int64, error) {
+	return 0, ErrOffline
+}
+
+func (offlineChain) GetNodePeer(context.Context, string) (string, error) {
+	return "", ErrOffline
+}
+
+func (offlineChain) QueryDidParams(context.Context) (string, error) {
+	return "", ErrOffline
+}
+
+func (offlineChain) QueryMetadata(context.Context, *types.MetadataProposal, int64) (*saotypes.QueryMetadataResponse, error) {
+	return nil, ErrOffline
+}
+
+func (offlineChain) GetModel(context.Context, string) (*modeltypes.QueryGetModelResponse, error) {
+	return nil, ErrOffline
+}
+
+func (offlineChain) GetMeta(context.Context, string) (*modeltypes.QueryGetMetadataResponse, error) {
+	return nil, ErrOffline
+}
+
+func (offlineChain) ListMetaByDid(context.Context, string) ([]modeltypes.Metadata, error) {
+	return nil, ErrOffline
+}
+
+func (offlineChain) GetBlock(context.Context, int64) (*coretypes.ResultBlock, error) {
+	return nil, ErrOffline
+}
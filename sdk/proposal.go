@@ -0,0 +1,565 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	cid "github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// queryValidBlocks is the number of blocks a query request stays valid.
+const queryValidBlocks = 200
+
+// ProposalParams are the chain and gateway parameters the proposals are built with. They let a client without
+// network access, e.g. on an air-gapped host, build and sign proposals with the parameters an online client
+// fetched beforehand, see SaoClientApi.ProposalParams.
+type ProposalParams struct {
+	// Provider is the address of the gateway the store proposals are addressed to.
+	Provider string `json:"provider"`
+	// GatewayPeer is the peer info the gateway registered on chain, which the query requests are addressed to.
+	GatewayPeer string `json:"gatewayPeer"`
+	// LastValidHeight is the last height at which the gateway accepts the query requests.
+	LastValidHeight uint64 `json:"lastValidHeight"`
+	// BlockTime converts the storage durations into blocks, 0 means the block time of the client, see BlockTime.
+	// An offline client has no block time unless Config.BlockTime is set.
+	BlockTime time.Duration `json:"blockTime"`
+}
+
+// ProposalParams fetches the parameters to build proposals for the gateway of the client, valid for validFor
+// blocks from the current height, 0 means as long as the query requests built by the client.
+func (sc *SaoClientApi) ProposalParams(ctx context.Context, validFor uint64) (*ProposalParams, error) {
+	if validFor == 0 {
+		validFor = queryValidBlocks
+	}
+
+	_, _, gatewayAddress, err := sc.pinGateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lastHeight, err := sc.client.GetLastHeight(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to query the latest height: %w", err)
+	}
+	peerInfo, err := sc.client.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProposalParams{
+		Provider:        gatewayAddress,
+		GatewayPeer:     peerInfo,
+		LastValidHeight: uint64(lastHeight) + validFor,
+		BlockTime:       sc.BlockTime(ctx),
+	}, nil
+}
+
+func (p ProposalParams) validateStore() error {
+	if p.Provider == "" || p.GatewayPeer == "" || p.LastValidHeight == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "store proposals need the provider, the gateway peer and the last valid height")
+	}
+	return nil
+}
+
+// ProposalKind tells which request a SignedProposal submits.
+type ProposalKind string
+
+const (
+	ProposalCreateModel ProposalKind = "createModel"
+	ProposalCreateFile  ProposalKind = "createFile"
+	ProposalUpdateModel ProposalKind = "updateModel"
+	ProposalRenew       ProposalKind = "renew"
+	ProposalTerminate   ProposalKind = "terminate"
+	ProposalPermission  ProposalKind = "permission"
+)
+
+// SignedProposal is a signed request built by one of the Build*Proposal methods, to be submitted later with
+// SubmitProposal, possibly by another client. It is serialized to JSON with Marshal and UnmarshalSignedProposal.
+type SignedProposal struct {
+	Kind ProposalKind `json:"kind"`
+	// Query is the query request sent along the store proposals.
+	Query      *types.MetadataProposal       `json:"query,omitempty"`
+	Store      *types.OrderStoreProposal     `json:"store,omitempty"`
+	Renew      *types.OrderRenewProposal     `json:"renew,omitempty"`
+	Terminate  *types.OrderTerminateProposal `json:"terminate,omitempty"`
+	Permission *types.PermissionProposal     `json:"permission,omitempty"`
+	// Content is the content of the model created by a ProposalCreateModel, or the patch of a ProposalUpdateModel.
+	Content []byte `json:"content,omitempty"`
+}
+
+func (p *SignedProposal) Marshal() ([]byte, error) {
+	content, err := json.Marshal(p)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return content, nil
+}
+
+// UnmarshalSignedProposal parses a proposal serialized by SignedProposal.Marshal.
+func UnmarshalSignedProposal(content []byte) (*SignedProposal, error) {
+	p := &SignedProposal{}
+	err := json.Unmarshal(content, p)
+	if err != nil {
+		return nil, types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	err = p.validate()
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// validate checks that the proposal holds what its kind submits.
+func (p *SignedProposal) validate() error {
+	var complete bool
+	switch p.Kind {
+	case ProposalCreateModel, ProposalCreateFile, ProposalUpdateModel:
+		complete = p.Query != nil && p.Store != nil
+	case ProposalRenew:
+		complete = p.Renew != nil
+	case ProposalTerminate:
+		complete = p.Terminate != nil
+	case ProposalPermission:
+		complete = p.Permission != nil
+	default:
+		return types.Wrapf(types.ErrInvalidParameters, "unknown proposal kind %q", p.Kind)
+	}
+	if !complete {
+		return types.Wrapf(types.ErrInvalidParameters, "incomplete %s proposal", p.Kind)
+	}
+	return nil
+}
+
+// SubmitResult is the outcome of a submitted proposal.
+type SubmitResult struct {
+	// Alias and DataId identify the model a ProposalCreateModel or ProposalCreateFile created, a ProposalUpdateModel
+	// updated, or a ProposalTerminate deleted.
+	Alias  string
+	DataId string
+	// CommitId is the commit a ProposalUpdateModel added.
+	CommitId string
+	// Renewed holds a result per data id of a ProposalRenew.
+	Renewed []RenewResult
+}
+
+// SubmitProposal submits a proposal signed by a Build*Proposal method, possibly on another host. Store proposals
+// are addressed to a gateway, they have to be submitted through a client connected to that gateway.
+// A proposal whose query request expired has to be built and signed again.
+func (sc *SaoClientApi) SubmitProposal(ctx context.Context, p *SignedProposal) (*SubmitResult, error) {
+	err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Kind {
+	case ProposalCreateModel, ProposalCreateFile, ProposalUpdateModel:
+		gateway, _, gatewayAddress, err := sc.pinGateway(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if p.Store.Proposal.Provider != gatewayAddress {
+			return nil, types.Wrapf(types.ErrInvalidParameters, "the proposal is addressed to the gateway %s, not to %s",
+				p.Store.Proposal.Provider, gatewayAddress)
+		}
+
+		switch p.Kind {
+		case ProposalUpdateModel:
+			resp, err := gateway.ModelUpdate(ctx, p.Query, p.Store, 0, p.Content)
+			if err != nil {
+				return nil, err
+			}
+			return &SubmitResult{Alias: resp.Alias, DataId: resp.DataId, CommitId: resp.CommitId}, nil
+		case ProposalCreateFile:
+			resp, err := gateway.ModelCreateFile(ctx, p.Query, p.Store, 0)
+			if err != nil {
+				return nil, err
+			}
+			return &SubmitResult{Alias: resp.Alias, DataId: resp.DataId}, nil
+		default:
+			resp, err := gateway.ModelCreate(ctx, p.Query, p.Store, 0, p.Content)
+			if err != nil {
+				return nil, err
+			}
+			return &SubmitResult{Alias: resp.Alias, DataId: resp.DataId}, nil
+		}
+	case ProposalRenew:
+		res, err := sc.client.ModelRenewOrder(ctx, p.Renew, true)
+		if err != nil {
+			return nil, err
+		}
+		return &SubmitResult{Renewed: sc.renewResults(ctx, p.Renew.Proposal.Data, res.Results)}, nil
+	case ProposalTerminate:
+		resp, err := sc.client.ModelDelete(ctx, p.Terminate, true)
+		if err != nil {
+			return nil, err
+		}
+		return &SubmitResult{DataId: resp.DataId}, nil
+	default:
+		_, err := sc.client.ModelUpdatePermission(ctx, p.Permission, true)
+		if err != nil {
+			return nil, err
+		}
+		return &SubmitResult{}, nil
+	}
+}
+
+// BuildCreateModelProposal builds and signs the creation of the model described by req, to be submitted with
+// SubmitProposal. Public models need a permission proposal for the public dids, see SetPublicPermission.
+func (sc *SaoClientApi) BuildCreateModelProposal(ctx context.Context, req CreateModelRequest, params ProposalParams) (*SignedProposal, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if req.IsPublic {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "build a permission proposal for the public dids to create a public model")
+	}
+	err = params.validateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal, content, err := sc.createModelProposal(ctx, didManager, req, params.BlockTime)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := sc.signStoreProposal(ctx, didManager, proposal, "", params)
+	if err != nil {
+		return nil, err
+	}
+	signed.Kind, signed.Content = ProposalCreateModel, content
+	return signed, nil
+}
+
+// BuildCreateFileProposal builds and signs the creation of the file model described by req, to be submitted
+// with SubmitProposal. The file has to be uploaded to the gateway of the proposal beforehand.
+func (sc *SaoClientApi) BuildCreateFileProposal(ctx context.Context, req CreateFileRequest, params ProposalParams) (*SignedProposal, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	err = params.validateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal, err := sc.createFileProposal(ctx, didManager, req, params.BlockTime)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := sc.signStoreProposal(ctx, didManager, proposal, "", params)
+	if err != nil {
+		return nil, err
+	}
+	signed.Kind = ProposalCreateFile
+	return signed, nil
+}
+
+// BuildUpdateModelProposal builds and signs the update described by req, to be submitted with SubmitProposal.
+// Without the chain, req.Keyword must be the data id of the model, and KeepStorage needs Duration and Replica.
+// The proposal does not carry the alias of the model, which the update keeps.
+func (sc *SaoClientApi) BuildUpdateModelProposal(ctx context.Context, req UpdateModelRequest, params ProposalParams) (*SignedProposal, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsDataId(req.Keyword) {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "update proposals need the data id of the model, not %q", req.Keyword)
+	}
+	if req.Duration == 0 || req.Replica == 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "update proposals can not keep the storage terms of the model, set Duration and Replica")
+	}
+	err = params.validateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	blocks, err := sc.proposalBlocks(ctx, req.Duration, params.BlockTime)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := updateModelProposal(didManager, req, req.Keyword, "", blocks, int32(req.Replica))
+	if err != nil {
+		return nil, err
+	}
+	signed, err := sc.signStoreProposal(ctx, didManager, proposal, req.GroupId, params)
+	if err != nil {
+		return nil, err
+	}
+	signed.Kind, signed.Content = ProposalUpdateModel, []byte(req.Patch)
+	return signed, nil
+}
+
+// BuildRenewProposal builds and signs the renewal described by req, to be submitted with SubmitProposal.
+// Only params.BlockTime is used.
+func (sc *SaoClientApi) BuildRenewProposal(ctx context.Context, req RenewRequest, params ProposalParams) (*SignedProposal, error) {
+	err := req.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	blocks, err := sc.proposalBlocks(ctx, req.Duration, params.BlockTime)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := saotypes.RenewProposal{
+		Owner:    didManager.Id,
+		Duration: blocks,
+		Timeout:  int32(req.Delay),
+		Data:     req.DataIds,
+	}
+	jws, err := signProposal(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedProposal{
+		Kind: ProposalRenew,
+		Renew: &types.OrderRenewProposal{
+			Proposal:     proposal,
+			JwsSignature: jws,
+		},
+	}, nil
+}
+
+// BuildTerminateProposal builds and signs the deletion of the model with the given data id, to be submitted
+// with SubmitProposal.
+func (sc *SaoClientApi) BuildTerminateProposal(ctx context.Context, dataId string) (*SignedProposal, error) {
+	if dataId == "" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "dataId is missing")
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal := saotypes.TerminateProposal{
+		Owner:  didManager.Id,
+		DataId: dataId,
+	}
+	jws, err := signProposal(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedProposal{
+		Kind: ProposalTerminate,
+		Terminate: &types.OrderTerminateProposal{
+			Proposal:     proposal,
+			JwsSignature: jws,
+		},
+	}, nil
+}
+
+// BuildPermissionProposal builds and signs the permission update of the model with the given data id, to be
+// submitted with SubmitProposal. Like UpdatePermission, it does not add the dids to the recipients
+// of encrypted content.
+func (sc *SaoClientApi) BuildPermissionProposal(ctx context.Context, dataId string, readonlyDids []string, readwriteDids []string) (*SignedProposal, error) {
+	if dataId == "" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "data id is missing")
+	}
+
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal := saotypes.PermissionProposal{
+		Owner:         didManager.Id,
+		DataId:        dataId,
+		ReadonlyDids:  readonlyDids,
+		ReadwriteDids: readwriteDids,
+	}
+	jws, err := signProposal(didManager, &proposal)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedProposal{
+		Kind: ProposalPermission,
+		Permission: &types.PermissionProposal{
+			Proposal:     proposal,
+			JwsSignature: jws,
+		},
+	}, nil
+}
+
+// signStoreProposal signs the store proposal addressed to the provider of params, along with its query request
+// for the data id of the proposal, in the given group.
+func (sc *SaoClientApi) signStoreProposal(ctx context.Context, didManager *did.DidManager, proposal saotypes.Proposal, groupId string, params ProposalParams) (*SignedProposal, error) {
+	proposal.Provider = params.Provider
+	store, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
+	if err != nil {
+		return nil, err
+	}
+
+	queryProposal := saotypes.QueryProposal{
+		Owner:   didManager.Id,
+		Keyword: proposal.DataId,
+		GroupId: groupId,
+	}
+	query, err := signQueryRequest(didManager, queryProposal, params.GatewayPeer, params.LastValidHeight)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedProposal{Query: query, Store: store}, nil
+}
+
+// createModelProposal builds the store proposal of a new model, without provider, and returns it with
+// the content to store, encrypted if req.Encrypt is set. A zero blockTime means the block time of the client.
+func (sc *SaoClientApi) createModelProposal(ctx context.Context, didManager *did.DidManager, req CreateModelRequest, blockTime time.Duration) (saotypes.Proposal, []byte, error) {
+	var err error
+	contentBytes := []byte(req.Content)
+	if req.Encrypt {
+		contentBytes, err = sc.EncryptContent(ctx, contentBytes, req.Recipients...)
+		if err != nil {
+			return saotypes.Proposal{}, nil, err
+		}
+	}
+	contentCid, err := CalculateCid(contentBytes)
+	if err != nil {
+		return saotypes.Proposal{}, nil, err
+	}
+
+	blocks, err := sc.proposalBlocks(ctx, req.Duration, blockTime)
+	if err != nil {
+		return saotypes.Proposal{}, nil, err
+	}
+
+	dataId := utils.GenerateDataId(didManager.Id + req.GroupId)
+	proposal := saotypes.Proposal{
+		DataId:     dataId,
+		Owner:      didManager.Id,
+		GroupId:    req.GroupId,
+		Duration:   blocks,
+		Replica:    int32(req.Replica),
+		Timeout:    int32(req.Delay),
+		Alias:      req.Name,
+		Tags:       []string{""},
+		Cid:        contentCid.String(),
+		CommitId:   dataId,
+		Rule:       "",
+		Size_:      uint64(len(contentBytes)),
+		Operation:  1,
+		ExtendInfo: "",
+	}
+	if proposal.Alias == "" {
+		proposal.Alias = proposal.Cid
+	}
+	return proposal, contentBytes, nil
+}
+
+// createFileProposal builds the store proposal of a new file model, without provider. A zero blockTime means
+// the block time of the client.
+func (sc *SaoClientApi) createFileProposal(ctx context.Context, didManager *did.DidManager, req CreateFileRequest, blockTime time.Duration) (saotypes.Proposal, error) {
+	contentCid, err := cid.Decode(req.Cid)
+	if err != nil {
+		return saotypes.Proposal{}, types.Wrap(types.ErrInvalidCid, err)
+	}
+
+	blocks, err := sc.proposalBlocks(ctx, req.Duration, blockTime)
+	if err != nil {
+		return saotypes.Proposal{}, err
+	}
+
+	dataId := utils.GenerateDataId(didManager.Id + req.GroupId)
+	return saotypes.Proposal{
+		DataId:     dataId,
+		Owner:      didManager.Id,
+		GroupId:    req.GroupId,
+		Duration:   blocks,
+		Replica:    int32(req.Replica),
+		Timeout:    int32(req.Delay),
+		Alias:      req.FileName,
+		Tags:       []string{},
+		Cid:        contentCid.String(),
+		CommitId:   dataId,
+		Rule:       "",
+		Operation:  1,
+		ExtendInfo: "",
+		Size_:      req.Size,
+	}, nil
+}
+
+// updateModelProposal builds the store proposal of the update described by req of the model with the given data
+// id and alias, without provider.
+func updateModelProposal(didManager *did.DidManager, req UpdateModelRequest, dataId string, alias string, duration uint64, replica int32) (saotypes.Proposal, error) {
+	newCid, err := cid.Decode(req.Cid)
+	if err != nil {
+		return saotypes.Proposal{}, types.Wrapf(types.ErrInvalidCid, "invalid cid: %v", req.Cid)
+	}
+
+	operation := uint32(1)
+	if req.Force {
+		operation = 2
+	}
+
+	return saotypes.Proposal{
+		Owner:      didManager.Id,
+		GroupId:    req.GroupId,
+		Duration:   duration,
+		Replica:    replica,
+		Timeout:    int32(req.Delay),
+		DataId:     dataId,
+		Alias:      alias,
+		Tags:       []string{},
+		Cid:        newCid.String(),
+		CommitId:   req.CommitId + "|" + utils.GenerateCommitId(didManager.Id+req.GroupId),
+		Rule:       "",
+		Operation:  operation,
+		Size_:      req.Size,
+		ExtendInfo: "",
+	}, nil
+}
+
+// proposalBlocks converts d into blocks at blockTime, or at the block time of the client if zero. An offline
+// client fails rather than guessing the block time it can not observe.
+func (sc *SaoClientApi) proposalBlocks(ctx context.Context, d time.Duration, blockTime time.Duration) (uint64, error) {
+	if blockTime > 0 {
+		return DurationToBlocks(d, blockTime)
+	}
+	if sc.offline && sc.blockTime <= 0 {
+		return 0, xerrors.Errorf("the block time is unknown, set ProposalParams.BlockTime or Config.BlockTime: %w", ErrOffline)
+	}
+	return sc.storageBlocks(ctx, d)
+}
+
+// signProposal signs the marshalled proposal with the did.
+func signProposal(didManager *did.DidManager, proposal interface{ Marshal() ([]byte, error) }) (saotypes.JwsSignature, error) {
+	proposalBytes, err := proposal.Marshal()
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	jws, err := didManager.CreateJWS(proposalBytes)
+	if err != nil {
+		return saotypes.JwsSignature{}, types.Wrap(types.ErrCreateJwsFailed, err)
+	}
+	return saotypes.JwsSignature{
+		Protected: jws.Signatures[0].Protected,
+		Signature: jws.Signatures[0].Signature,
+	}, nil
+}
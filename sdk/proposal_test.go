@@ -0,0 +1,146 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestOfflineProposals(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	online := newClient(t, srv, home, "alice")
+	offline := sdk.NewOfflineSaoClientApi("alice", home)
+	params, err := online.ProposalParams(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Provider != sdktest.GatewayAddress || params.BlockTime != time.Second {
+		t.Fatalf("got %+v", params)
+	}
+
+	// proposals travel serialized from the offline host to the online one
+	submit := func(p *sdk.SignedProposal, err error) *sdk.SubmitResult {
+		t.Helper()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := p.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err = sdk.UnmarshalSignedProposal(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := online.SubmitProposal(ctx, p)
+		if err != nil {
+			t.Fatalf("%s: %v", p.Kind, err)
+		}
+		return res
+	}
+
+	req := sdk.CreateModelRequest{Content: `{"x":1}`, GroupId: "g", Name: "off", Duration: 24 * time.Hour, Replica: 1}
+	created := submit(offline.BuildCreateModelProposal(ctx, req, *params))
+	if created.Alias != "off" || load(t, online, created.DataId) != `{"x":1}` {
+		t.Fatalf("got %+v", created)
+	}
+
+	res, err := online.Download(ctx, created.DataId, io.Discard, sdk.DownloadOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, c, size, err := online.PatchGen(`{"x":1}`, `{"x":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := sdk.UpdateModelRequest{Keyword: created.DataId, GroupId: "g", CommitId: res.CommitId, Patch: patch, Cid: c.String(), Size: uint64(size), Duration: time.Hour, Replica: 1}
+	updated := submit(offline.BuildUpdateModelProposal(ctx, update, *params))
+	if updated.CommitId == "" || load(t, online, "off") != `{"x":2}` {
+		t.Fatalf("got %+v", updated)
+	}
+
+	content := []byte("abcd")
+	fileCid, err := srv.Store.PutBlob(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := sdk.CreateFileRequest{FileName: "f.txt", Cid: fileCid.String(), GroupId: "g", Size: uint64(len(content)), Duration: time.Hour, Replica: 1}
+	if res := submit(offline.BuildCreateFileProposal(ctx, file, *params)); res.Alias != "f.txt" {
+		t.Fatalf("got %+v", res)
+	}
+
+	renewed := submit(offline.BuildRenewProposal(ctx, sdk.RenewRequest{DataIds: []string{created.DataId}, Duration: time.Hour}, *params))
+	if len(renewed.Renewed) != 1 || renewed.Renewed[0].Status != sdk.RenewStatusRenewed {
+		t.Fatalf("got %+v", renewed.Renewed)
+	}
+
+	submit(offline.BuildPermissionProposal(ctx, created.DataId, []string{"did:key:zQ3shabc"}, nil))
+
+	submit(offline.BuildTerminateProposal(ctx, created.DataId))
+	if _, err := online.Load(ctx, created.DataId, "", "", "g"); !errors.Is(err, sdk.ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrNotFound)
+	}
+}
+
+func TestOfflineProposalErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	online := newClient(t, srv, home, "alice")
+	offline := sdk.NewOfflineSaoClientApi("alice", home)
+	params, err := online.ProposalParams(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := offline.ProposalParams(ctx, 0); !errors.Is(err, sdk.ErrOffline) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrOffline)
+	}
+	if _, _, err := offline.CreateModel(ctx, `{"a":1}`, "g", 1, 100, "m", 1, false); !errors.Is(err, sdk.ErrOffline) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrOffline)
+	}
+
+	req := sdk.CreateModelRequest{Content: `{"x":1}`, GroupId: "g", Name: "off", Duration: time.Hour, Replica: 1}
+	unknown := *params
+	unknown.BlockTime = 0
+	if _, err := offline.BuildCreateModelProposal(ctx, req, unknown); !errors.Is(err, sdk.ErrOffline) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrOffline)
+	}
+
+	other := *params
+	other.Provider = "sao1othergateway"
+	p, err := offline.BuildCreateModelProposal(ctx, req, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := online.SubmitProposal(ctx, p); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+
+	update := sdk.UpdateModelRequest{Keyword: "aed2548c-c9fb-11f1-9f09-3adc08ccb204", GroupId: "g", CommitId: "aed2548c-c9fb-11f1-9f09-3adc08ccb204", Patch: "[]", Cid: "QmNRwNKPCo7tufiPf7zBwJhKdswzFLyWHE5am6tAXP7EbB", Size: 7, Duration: time.Hour, Replica: 1}
+	for name, mutate := range map[string]func(*sdk.UpdateModelRequest){
+		"alias":        func(r *sdk.UpdateModelRequest) { r.Keyword = "m" },
+		"keep storage": func(r *sdk.UpdateModelRequest) { r.Duration, r.KeepStorage = 0, true },
+	} {
+		r := update
+		mutate(&r)
+		if _, err := offline.BuildUpdateModelProposal(ctx, r, *params); !errors.Is(err, types.ErrInvalidParameters) {
+			t.Errorf("%s: got %v, want %v", name, err, types.ErrInvalidParameters)
+		}
+	}
+
+	if _, err := sdk.UnmarshalSignedProposal([]byte(`{"kind":"renew"}`)); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+}
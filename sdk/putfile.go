@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	cid "github.com/ipfs/go-cid"
+)
+
+// PutFileOptions describe the file model created by PutFile, zero Duration, Delay and Replica take the defaults.
+type PutFileOptions struct {
+	// FileName is the alias of the file model, the base name of the file if empty.
+	FileName string
+	GroupId  string
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the order.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+	// Multiaddr is the file transport address of the gateway, the addresses the gateway registered on chain if empty.
+	// The address of Protocol is picked from a comma separated list.
+	Multiaddr string
+	// Protocol is the file transport protocol, udp or tcp, Config.Transport if empty.
+	Protocol string
+	// Progress is called after every chunk the gateway acknowledged.
+	Progress func(UploadProgress)
+	// Encrypt stores the file encrypted for the client's did and Recipients, see EncryptContent.
+	Encrypt    bool
+	Recipients []string
+}
+
+// PutFile uploads the file at path and creates its file model with the size of the file. The cid acknowledged
+// by the gateway is checked against the one computed locally before the upload. It returns the alias, the data
+// id and the cid of the file.
+func (sc *SaoClientApi) PutFile(ctx context.Context, path string, opts PutFileOptions) (string, string, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", "", types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", "", "", types.Wrapf(types.ErrInvalidParameters, "%s is not a regular file", path)
+	}
+	if info.Size() == 0 {
+		return "", "", "", types.Wrapf(types.ErrInvalidParameters, "%s is empty", path)
+	}
+
+	req := CreateFileRequest{
+		FileName: opts.FileName,
+		GroupId:  opts.GroupId,
+		Size:     uint64(info.Size()),
+		Duration: opts.Duration,
+		Delay:    opts.Delay,
+		Replica:  opts.Replica,
+	}
+	if req.FileName == "" {
+		req.FileName = filepath.Base(path)
+	}
+
+	// an encrypted file is checked against the cid acknowledged by the gateway only, the envelope is random.
+	var localCid cid.Cid
+	if !opts.Encrypt {
+		localCid, err = fileCid(ctx, path)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+
+	multiaddr := opts.Multiaddr
+	if multiaddr == "" {
+		multiaddr, err = sc.gatewayMultiaddr(ctx, opts.Protocol)
+		if err != nil {
+			return "", "", "", err
+		}
+	}
+
+	conn, err := sc.dialTransport(ctx, multiaddr, opts.Protocol)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer conn.Close()
+
+	uploadedCid, size, err := sc.uploadPath(ctx, conn, path, UploadOptions{
+		Progress:   opts.Progress,
+		Encrypt:    opts.Encrypt,
+		Recipients: opts.Recipients,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", "", "", ctx.Err()
+		}
+		return "", "", "", types.Wrapf(types.ErrStoreFailed, "failed to upload %s: %v", path, err)
+	}
+	if !opts.Encrypt && (!uploadedCid.Equals(localCid) || size != info.Size()) {
+		return "", "", "", types.Wrapf(types.ErrInvalidCid, "%s changed during the upload, uploaded %s of %d bytes, expected %s of %d bytes",
+			path, uploadedCid, size, localCid, info.Size())
+	}
+	req.Size = uint64(size)
+	req.Cid = uploadedCid.String()
+
+	alias, dataId, err := sc.CreateFileWithRequest(ctx, req)
+	if err != nil {
+		return "", "", "", err
+	}
+	return alias, dataId, req.Cid, nil
+}
+
+// gatewayMultiaddr returns the file transport address of the protocol, Config.Transport if empty, among the
+// comma separated addresses the gateway registered on chain.
+func (sc *SaoClientApi) gatewayMultiaddr(ctx context.Context, protocol string) (string, error) {
+	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
+	if err != nil {
+		return "", err
+	}
+	peers, err := sc.client.GetNodePeer(ctx, gatewayAddress)
+	if err != nil {
+		return "", err
+	}
+	if protocol == "" {
+		protocol = sc.transport
+	}
+	return selectMultiaddr(peers, protocol)
+}
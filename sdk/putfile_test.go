@@ -0,0 +1,41 @@
+package sdk_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+)
+
+func TestPutFile(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	path := filepath.Join(t.TempDir(), "f.txt")
+	writeFile(t, path, "hello file")
+
+	alias, dataId, c, err := client.PutFile(ctx, path, sdk.PutFileOptions{GroupId: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := sdk.CalculateCid([]byte("hello file"))
+	if alias != "f.txt" || c != want.String() {
+		t.Fatalf("got %s and %s, want f.txt and %s", alias, c, want)
+	}
+	if got := load(t, client, dataId); got != "hello file" {
+		t.Fatalf("got %s", got)
+	}
+
+	for _, protocol := range []string{"tcp", "udp"} {
+		if _, _, _, err := client.PutFile(ctx, path, sdk.PutFileOptions{GroupId: "g", FileName: protocol, Protocol: protocol}); err != nil {
+			t.Fatalf("%s: %v", protocol, err)
+		}
+	}
+
+	if _, _, _, err := client.PutFile(ctx, filepath.Dir(path), sdk.PutFileOptions{GroupId: "g"}); err == nil {
+		t.Fatal("put a directory as a file")
+	}
+}
@@ -0,0 +1,155 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	"golang.org/x/xerrors"
+)
+
+// The remote signing protocol: the client posts a JSON signRequest to <endpoint>/address or <endpoint>/sign
+// and gets a JSON signResponse back, with the bearer token in the Authorization header if one is configured.
+// Failures are answered with a non 2xx status and the reason in Error.
+const (
+	signerAddressPath = "/address"
+	signerSignPath    = "/sign"
+)
+
+type signRequest struct {
+	KeyName string `json:"keyName"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+type signResponse struct {
+	Address   string `json:"address,omitempty"`
+	Signature []byte `json:"signature,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RemoteSigner delegates to a signing service over HTTP, which holds the keys, see NewSignerHandler.
+type RemoteSigner struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+var _ Signer = (*RemoteSigner)(nil)
+
+// NewRemoteSigner creates a RemoteSigner for the service at endpoint, e.g. http://127.0.0.1:8888,
+// sending token as bearer token unless empty.
+func NewRemoteSigner(endpoint string, token string) *RemoteSigner {
+	return &RemoteSigner{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		client:   &http.Client{},
+	}
+}
+
+func (s *RemoteSigner) GetAddress(ctx context.Context, keyName string) (string, error) {
+	resp, err := s.call(ctx, signerAddressPath, signRequest{KeyName: keyName})
+	if err != nil {
+		return "", types.Wrap(types.ErrGetAddressFailed, err)
+	}
+	if resp.Address == "" {
+		return "", types.Wrapf(types.ErrGetAddressFailed, "signer returned no address for %s", keyName)
+	}
+	return resp.Address, nil
+}
+
+func (s *RemoteSigner) Sign(ctx context.Context, keyName string, payload []byte) ([]byte, error) {
+	resp, err := s.call(ctx, signerSignPath, signRequest{KeyName: keyName, Payload: payload})
+	if err != nil {
+		return nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+	if len(resp.Signature) == 0 {
+		return nil, types.Wrapf(types.ErrSignedFailed, "signer returned no signature for %s", keyName)
+	}
+	return resp.Signature, nil
+}
+
+func (s *RemoteSigner) call(ctx context.Context, path string, req signRequest) (*signResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	httpResp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp signResponse
+	err = json.NewDecoder(io.LimitReader(httpResp.Body, 1<<20)).Decode(&resp)
+	if httpResp.StatusCode/100 != 2 {
+		if err == nil && resp.Error != "" {
+			return nil, xerrors.Errorf("signer answered %s: %s", httpResp.Status, resp.Error)
+		}
+		return nil, xerrors.Errorf("signer answered %s", httpResp.Status)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("invalid signer response: %w", err)
+	}
+	return &resp, nil
+}
+
+// NewSignerHandler serves the keys of signer to RemoteSigner clients, e.g. a MemorySigner as a local stand-in
+// of a signing service. Requests without the bearer token are rejected, unless token is empty.
+func NewSignerHandler(signer Signer, token string) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(path string, serve func(ctx context.Context, req signRequest) (signResponse, error)) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.Method != http.MethodPost {
+				writeSignResponse(w, http.StatusMethodNotAllowed, signResponse{Error: "expect POST"})
+				return
+			}
+			if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+				writeSignResponse(w, http.StatusUnauthorized, signResponse{Error: "invalid token"})
+				return
+			}
+
+			var req signRequest
+			err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req)
+			if err != nil {
+				writeSignResponse(w, http.StatusBadRequest, signResponse{Error: err.Error()})
+				return
+			}
+			resp, err := serve(r.Context(), req)
+			if err != nil {
+				writeSignResponse(w, http.StatusBadRequest, signResponse{Error: err.Error()})
+				return
+			}
+			writeSignResponse(w, http.StatusOK, resp)
+		})
+	}
+
+	handle(signerAddressPath, func(ctx context.Context, req signRequest) (signResponse, error) {
+		address, err := signer.GetAddress(ctx, req.KeyName)
+		return signResponse{Address: address}, err
+	})
+	handle(signerSignPath, func(ctx context.Context, req signRequest) (signResponse, error) {
+		sig, err := signer.Sign(ctx, req.KeyName, req.Payload)
+		return signResponse{Signature: sig}, err
+	})
+	return mux
+}
+
+func writeSignResponse(w http.ResponseWriter, status int, resp signResponse) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,77 @@
+package sdk
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// RenewStatus is the outcome of renewing a single model.
+type RenewStatus int
+
+const (
+	// RenewStatusFailed means the model was not renewed, see RenewResult.Message for the reason.
+	RenewStatusFailed RenewStatus = iota
+	// RenewStatusRenewed means a new order extends the storage of the model.
+	RenewStatusRenewed
+	// RenewStatusAlreadyRenewed means the gateway reported the model as renewed without a new order.
+	RenewStatusAlreadyRenewed
+)
+
+func (s RenewStatus) String() string {
+	switch s {
+	case RenewStatusRenewed:
+		return "renewed"
+	case RenewStatusAlreadyRenewed:
+		return "already-renewed"
+	default:
+		return "failed"
+	}
+}
+
+// RenewResult is the result of renewing a single model.
+type RenewResult struct {
+	DataId string
+	Status RenewStatus
+	// OrderId is the id of the renew order, 0 if no order was created or the message does not carry it.
+	OrderId uint64
+	// ExpireHeight is the height the model expires at after the renewal, 0 if unknown.
+	ExpireHeight uint64
+	// Message is the raw message returned by the gateway.
+	Message string
+}
+
+var (
+	renewOrderIdPattern = regexp.MustCompile(`(?i)order[ _-]?id\s*[=:]\s*(\d+)`)
+	renewFailedPattern  = regexp.MustCompile(`(?i)\b(failed|failure|error|invalid|not found|no permission|expired)\b`)
+	renewSuccessPattern = regexp.MustCompile(`(?i)^\s*(success|ok)\b`)
+	renewAlreadyPattern = regexp.MustCompile(`(?i)\balready\b.*\brenew`)
+)
+
+// ParseRenewResult classifies the message the gateway returned for a data id. Failure markers win over
+// success markers, and a message which matches neither is reported as failed rather than renewed.
+func ParseRenewResult(dataId string, message string) RenewResult {
+	result := RenewResult{
+		DataId:  dataId,
+		Status:  RenewStatusFailed,
+		Message: message,
+	}
+
+	switch {
+	case renewFailedPattern.MatchString(message):
+	case renewAlreadyPattern.MatchString(message):
+		result.Status = RenewStatusAlreadyRenewed
+	case renewSuccessPattern.MatchString(message):
+		result.Status = RenewStatusRenewed
+	}
+
+	if result.Status == RenewStatusFailed {
+		return result
+	}
+	if match := renewOrderIdPattern.FindStringSubmatch(message); match != nil {
+		orderId, err := strconv.ParseUint(match[1], 10, 64)
+		if err == nil {
+			result.OrderId = orderId
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package sdk
+
+import "testing"
+
+func TestParseRenewResult(t *testing.T) {
+	cases := []struct {
+		name    string
+		message string
+		status  RenewStatus
+		orderId uint64
+	}{
+		// renewed
+		{"success with order id", "SUCCESS: orderId=12", RenewStatusRenewed, 12},
+		{"lower case with order_id", "success: order_id: 7", RenewStatusRenewed, 7},
+		{"ok with order id", "OK order-id=3", RenewStatusRenewed, 3},
+		{"success without order id", "SUCCESS", RenewStatusRenewed, 0},
+		{"leading spaces", "  SUCCESS: orderId=5", RenewStatusRenewed, 5},
+
+		// already renewed
+		{"already renewed", "order already renewed", RenewStatusAlreadyRenewed, 0},
+		{"already renewed with order id", "SUCCESS: already renewed, orderId=9", RenewStatusAlreadyRenewed, 9},
+
+		// failed
+		{"failed", "FAILED: dataId 1b0c not found", RenewStatusFailed, 0},
+		{"failed with order id", "FAILED: invalid order id: 3", RenewStatusFailed, 0},
+		{"no permission", "FAILED: no permission to renew the model 1b0c: no permission", RenewStatusFailed, 0},
+		{"rpc error", "rpc error: code = NotFound desc = FAILED: dataId 1b0c not found", RenewStatusFailed, 0},
+		{"success with error", "SUCCESS: orderId=4, error: expired", RenewStatusFailed, 0},
+		{"already renewed failure", "failed: already renewed", RenewStatusFailed, 0},
+
+		// unknown or changed formats are not reported as renewed
+		{"empty", "", RenewStatusFailed, 0},
+		{"unknown", "renewal queued", RenewStatusFailed, 0},
+		{"success not leading", "renewal: success", RenewStatusFailed, 0},
+		{"success as a prefix", "SUCCESSFUL orderId=2", RenewStatusFailed, 0},
+		{"json", `{"status":"success","orderId":8}`, RenewStatusFailed, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := ParseRenewResult("1b0c", c.message)
+			if result.Status != c.status || result.OrderId != c.orderId {
+				t.Fatalf("%q: got %s with order %d, want %s with order %d", c.message, result.Status, result.OrderId, c.status, c.orderId)
+			}
+			if result.DataId != "1b0c" || result.Message != c.message {
+				t.Fatalf("%q: got data id %q and message %q", c.message, result.DataId, result.Message)
+			}
+		})
+	}
+}
+
+func TestRenewStatusString(t *testing.T) {
+	for status, want := range map[RenewStatus]string{
+		RenewStatusRenewed:        "renewed",
+		RenewStatusAlreadyRenewed: "already-renewed",
+		RenewStatusFailed:         "failed",
+		RenewStatus(42):           "failed",
+	} {
+		if got := status.String(); got != want {
+			t.Errorf("%d: got %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package sdk
+
+import (
+	"math"
+	"time"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	cid "github.com/ipfs/go-cid"
+)
+
+// defaults applied to the zero fields of the request structs.
+const (
+	DefaultReplica  = 1
+	DefaultDelay    = Epochs(100)
+	DefaultDuration = 365 * 24 * time.Hour
+)
+
+func setStorageDefaults(duration *time.Duration, delay *Epochs, replica *uint64) {
+	if *duration == 0 {
+		*duration = DefaultDuration
+	}
+	if *delay == 0 {
+		*delay = DefaultDelay
+	}
+	if *replica == 0 {
+		*replica = DefaultReplica
+	}
+}
+
+func validateStorage(duration time.Duration, delay Epochs, replica uint64) error {
+	if duration < 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "negative duration %s", duration)
+	}
+	if delay > math.MaxInt32 {
+		return types.Wrapf(types.ErrInvalidParameters, "delay %d exceeds %d epochs", delay, math.MaxInt32)
+	}
+	if replica > math.MaxInt32 {
+		return types.Wrapf(types.ErrInvalidParameters, "replica %d exceeds %d", replica, math.MaxInt32)
+	}
+	return nil
+}
+
+// CreateModelRequest describes a new data model, zero Duration, Delay and Replica take the defaults.
+type CreateModelRequest struct {
+	Content string
+	GroupId string
+	// Name is the alias of the model, the content cid is used if empty.
+	Name     string
+	IsPublic bool
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the order.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+	// Encrypt stores the content encrypted for the client's did and Recipients, see EncryptContent.
+	Encrypt    bool
+	Recipients []string
+}
+
+// Validate fills the zero fields with their defaults and checks the request.
+func (r *CreateModelRequest) Validate() error {
+	setStorageDefaults(&r.Duration, &r.Delay, &r.Replica)
+	if r.Content == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "must provide content")
+	}
+	if r.Encrypt && r.IsPublic {
+		return types.Wrapf(types.ErrInvalidParameters, "encrypted content can not be public")
+	}
+	return validateStorage(r.Duration, r.Delay, r.Replica)
+}
+
+// CreateFileRequest describes a file model for content uploaded by UploadFile, zero Duration, Delay and Replica take the defaults.
+type CreateFileRequest struct {
+	FileName string
+	Cid      string
+	GroupId  string
+	// Size is the file size in bytes.
+	Size uint64
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the order.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+}
+
+// Validate fills the zero fields with their defaults and checks the request.
+func (r *CreateFileRequest) Validate() error {
+	setStorageDefaults(&r.Duration, &r.Delay, &r.Replica)
+	if r.FileName == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "must provide file name")
+	}
+	if r.Size == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid size")
+	}
+	_, err := cid.Decode(r.Cid)
+	if err != nil {
+		return types.Wrap(types.ErrInvalidCid, err)
+	}
+	return validateStorage(r.Duration, r.Delay, r.Replica)
+}
+
+// UpdateModelRequest describes a patch on top of the commit CommitId of a model, see PatchGen for Patch, Cid and Size.
+// Zero Duration, Delay and Replica take the defaults, or the ones of the current order with KeepStorage.
+type UpdateModelRequest struct {
+	// Keyword is the data id or the alias of the model.
+	Keyword  string
+	GroupId  string
+	CommitId string
+	Patch    string
+	// Cid is the cid of the patched content.
+	Cid string
+	// Size is the size of the patched content.
+	Size uint64
+	// Force replaces the last commit instead of appending a new one.
+	Force bool
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the order.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+	// KeepStorage keeps the duration and the replica of the current order of the model when Duration and
+	// Replica are zero, instead of the defaults.
+	KeepStorage bool
+	// Encrypt updates an encrypted model: Patch, Cid and Size describe the change of the decrypted content, which
+	// is sealed again for the recipients of the envelope at CommitId. Without Encrypt, Patch applies to the stored
+	// content as it is, the envelope of an encrypted model.
+	Encrypt bool
+}
+
+// Validate fills the zero fields with their defaults and checks the request.
+func (r *UpdateModelRequest) Validate() error {
+	duration, replica := r.Duration, r.Replica
+	setStorageDefaults(&r.Duration, &r.Delay, &r.Replica)
+	if r.KeepStorage {
+		// the zero duration and replica are taken from the current order when the update is built
+		r.Duration, r.Replica = duration, replica
+	}
+	if r.Keyword == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "must provide keyword.")
+	}
+	if r.Size == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid size")
+	}
+	_, err := cid.Decode(r.Cid)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidCid, "invalid cid: %v", r.Cid)
+	}
+	return validateStorage(r.Duration, r.Delay, r.Replica)
+}
+
+// RenewRequest extends the storage of the given models, zero Duration and Delay take the defaults.
+type RenewRequest struct {
+	DataIds []string
+	// Duration is the extra storage duration, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the renewal.
+	Delay Epochs
+}
+
+// Validate fills the zero fields with their defaults and checks the request.
+func (r *RenewRequest) Validate() error {
+	replica := uint64(DefaultReplica)
+	setStorageDefaults(&r.Duration, &r.Delay, &replica)
+	if len(r.DataIds) <= 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "data ids is missing.")
+	}
+	return validateStorage(r.Duration, r.Delay, replica)
+}
+
+// UpdatePermissionRequest describes the dids granted to read or to write a model.
+type UpdatePermissionRequest struct {
+	DataId        string
+	ReadonlyDids  []string
+	ReadwriteDids []string
+	// Encrypted adds the dids to the recipients of the encrypted content of the model before the permission is
+	// granted, with an update keeping the storage terms of the model. The content is loaded for that.
+	Encrypted bool
+}
+
+// Validate checks the request.
+func (r *UpdatePermissionRequest) Validate() error {
+	if r.DataId == "" {
+		return types.Wrapf(types.ErrInvalidParameters, "data id is missing")
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	did "github.com/SaoNetwork/sao-did"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// RetryPolicy controls how the idempotent gateway and chain calls are retried when the gateway is unavailable,
+// and how many times an expired query request is rebuilt. The gateways report an expired request without
+// an error code, so a failed request counts as expired when the chain height passed its LastValidHeight.
+// Only the query requests of loads, updates and creations expire; renew, delete and permission proposals
+// carry no LastValidHeight and are never rebuilt.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 0 or 1 disables retries.
+	MaxAttempts int `toml:"MaxAttempts" yaml:"maxAttempts"`
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration `toml:"InitialBackoff" yaml:"initialBackoff"`
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration `toml:"MaxBackoff" yaml:"maxBackoff"`
+	// Multiplier grows the backoff after every attempt.
+	Multiplier float64 `toml:"Multiplier" yaml:"multiplier"`
+	// Jitter is the fraction of the backoff which is randomized, between 0 and 1.
+	Jitter float64 `toml:"Jitter" yaml:"jitter"`
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 || p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return types.Wrapf(types.ErrInvalidConfig, "retry policy must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return types.Wrapf(types.ErrInvalidConfig, "retry multiplier %v must not be less than 1", p.Multiplier)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return types.Wrapf(types.ErrInvalidConfig, "retry jitter %v must be between 0 and 1", p.Jitter)
+	}
+	return nil
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait before the given retry, starting at 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < retry && p.Multiplier > 1; i++ {
+		backoff *= p.Multiplier
+		if p.MaxBackoff > 0 && backoff >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// do runs call until it succeeds, fails with an error which is not worth retrying or runs out of attempts.
+func (p RetryPolicy) do(ctx context.Context, call func() error) error {
+	for retry := 0; ; retry++ {
+		if retry > 0 {
+			timer := time.NewTimer(p.backoff(retry))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		err := call()
+		if err == nil || !errors.Is(err, ErrGatewayUnavailable) || retry+1 >= p.attempts() {
+			return err
+		}
+	}
+}
+
+// withQueryRequest pins a gateway, signs a query request for proposal addressed to it and passes both to call,
+// along with the gateway address to use as the Provider of the proposals. As long as the request fails past
+// its LastValidHeight, it is rebuilt with a fresh one, up to the retry policy's MaxAttempts. With a GatewayPool,
+// call is run again on the next gateway when the pinned one is unavailable, if idempotent or if the request
+// was not delivered.
+func (sc *SaoClientApi) withQueryRequest(
+	ctx context.Context,
+	didManager *did.DidManager,
+	proposal saotypes.QueryProposal,
+	idempotent bool,
+	call func(gateway GatewayApi, gatewayAddress string, request *types.MetadataProposal) error,
+) error {
+	expired, failovers := 0, 0
+	for {
+		gateway, raw, gatewayAddress, err := sc.pinGateway(ctx)
+		if err != nil {
+			return err
+		}
+
+		request, err := sc.buildQueryRequest(ctx, didManager, proposal, sc.client, gatewayAddress)
+		if err != nil {
+			return err
+		}
+
+		err = call(gateway, gatewayAddress, request)
+		if err == nil {
+			return nil
+		}
+		if expired+1 < sc.retry.attempts() && sc.queryExpired(ctx, request) {
+			expired++
+			sc.log.Warnf("query proposal expired at height %d, rebuild it: %v", request.Proposal.LastValidHeight, err)
+			continue
+		}
+		if sc.pool != nil && sc.pool.report(raw, err, idempotent) && failovers+1 < sc.pool.Len() {
+			failovers++
+			continue
+		}
+		return err
+	}
+}
+
+// queryExpired reports whether the chain height passed the LastValidHeight of request.
+func (sc *SaoClientApi) queryExpired(ctx context.Context, request *types.MetadataProposal) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	height, err := sc.client.GetLastHeight(ctx)
+	return err == nil && request.Proposal.LastValidHeight < uint64(height)
+}
+
+// retryGateway retries the idempotent gateway calls.
+type retryGateway struct {
+	GatewayApi
+	policy RetryPolicy
+}
+
+func (g *retryGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (resp apitypes.LoadResp, err error) {
+	err = g.policy.do(ctx, func() error {
+		resp, err = g.GatewayApi.ModelLoad(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (g *retryGateway) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (resp apitypes.ShowCommitsResp, err error) {
+	err = g.policy.do(ctx, func() error {
+		resp, err = g.GatewayApi.ModelShowCommits(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (g *retryGateway) GetNodeAddress(ctx context.Context) (address string, err error) {
+	err = g.policy.do(ctx, func() error {
+		address, err = g.GatewayApi.GetNodeAddress(ctx)
+		return err
+	})
+	return address, err
+}
+
+// retryChain retries the chain queries.
+type retryChain struct {
+	ChainApi
+	policy RetryPolicy
+}
+
+func (c *retryChain) GetLastHeight(ctx context.Context) (height int64, err error) {
+	err = c.policy.do(ctx, func() error {
+		height, err = c.ChainApi.GetLastHeight(ctx)
+		return err
+	})
+	return height, err
+}
+
+func (c *retryChain) GetNodePeer(ctx context.Context, creator string) (peerInfo string, err error) {
+	err = c.policy.do(ctx, func() error {
+		peerInfo, err = c.ChainApi.GetNodePeer(ctx, creator)
+		return err
+	})
+	return peerInfo, err
+}
+
+func (c *retryChain) QueryDidParams(ctx context.Context) (dids string, err error) {
+	err = c.policy.do(ctx, func() error {
+		dids, err = c.ChainApi.QueryDidParams(ctx)
+		return err
+	})
+	return dids, err
+}
+
+func (c *retryChain) QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (resp *saotypes.QueryMetadataResponse, err error) {
+	err = c.policy.do(ctx, func() error {
+		resp, err = c.ChainApi.QueryMetadata(ctx, req, height)
+		return err
+	})
+	return resp, err
+}
+
+func (c *retryChain) GetModel(ctx context.Context, key string) (resp *modeltypes.QueryGetModelResponse, err error) {
+	err = c.policy.do(ctx, func() error {
+		resp, err = c.ChainApi.GetModel(ctx, key)
+		return err
+	})
+	return resp, err
+}
+
+func (c *retryChain) GetMeta(ctx context.Context, dataId string) (resp *modeltypes.QueryGetMetadataResponse, err error) {
+	err = c.policy.do(ctx, func() error {
+		resp, err = c.ChainApi.GetMeta(ctx, dataId)
+		return err
+	})
+	return resp, err
+}
+
+func (c *retryChain) ListMetaByDid(ctx context.Context, did string) (resp []modeltypes.Metadata, err error) {
+	err = c.policy.do(ctx, func() error {
+		resp, err = c.ChainApi.ListMetaByDid(ctx, did)
+		return err
+	})
+	return resp, err
+}
+
+func (c *retryChain) GetBlock(ctx context.Context, height int64) (resp *coretypes.ResultBlock, err error) {
+	err = c.policy.do(ctx, func() error {
+		resp, err = c.ChainApi.GetBlock(ctx, height)
+		return err
+	})
+	return resp, err
+}
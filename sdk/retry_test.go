@@ -0,0 +1,126 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice", sdk.WithRetryPolicy(sdk.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+		Multiplier:     2,
+	}))
+	dataId := createModel(t, client, `{"a":1}`, "m")
+
+	srv.FailRequests(2)
+	if got := load(t, client, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+
+	srv.FailRequests(10)
+	defer srv.FailRequests(0)
+	_, err := client.Load(ctx, dataId, "", "", "g")
+	if !errors.Is(err, sdk.ErrGatewayUnavailable) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrGatewayUnavailable)
+	}
+}
+
+// jumpChain advances the chain height each time the client resolves the gateway peer of a proposal,
+// so that the proposal expires before it reaches the gateway.
+type jumpChain struct {
+	*sdktest.Chain
+	store *sdktest.Store
+	jumps int
+}
+
+func (c *jumpChain) GetNodePeer(ctx context.Context, creator string) (string, error) {
+	if c.jumps > 0 {
+		c.jumps--
+		c.store.AdvanceHeight(1000)
+	}
+	return c.Chain.GetNodePeer(ctx, creator)
+}
+
+func TestRetryRebuildsExpiredProposal(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	if _, err := sdktest.CreateAccount(ctx, home, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	gateway, closer, err := sdk.NewNodeApi(ctx, srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+	chain := &jumpChain{Chain: srv.Chain, store: srv.Store}
+	client := sdk.NewSaoClientApiWithBackends(gateway, chain, "alice", home)
+	dataId := createModel(t, client, `{"a":1}`, "m")
+
+	// the first query expires, the rebuilt one does not
+	chain.jumps = 1
+	if got := load(t, client, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+
+	chain.jumps = 10
+	_, err = client.Load(ctx, dataId, "", "", "g")
+	if !errors.Is(err, sdk.ErrProposalExpired) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrProposalExpired)
+	}
+}
+
+// rejectingGateway rejects the first loads with err, after advancing the chain height by advance.
+type rejectingGateway struct {
+	*sdktest.Gateway
+	store   *sdktest.Store
+	rejects int
+	advance int64
+	err     error
+	loads   int
+}
+
+func (g *rejectingGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	g.loads++
+	if g.rejects > 0 {
+		g.rejects--
+		g.store.AdvanceHeight(g.advance)
+		return apitypes.LoadResp{}, g.err
+	}
+	return g.Gateway.ModelLoad(ctx, req)
+}
+
+func TestRetryDetectsExpiryOnChain(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	dataId := createModel(t, newClient(t, srv, home, "alice"), `{"a":1}`, "m")
+
+	// a request rejected past its LastValidHeight is rebuilt, whatever the gateway answered
+	gateway := &rejectingGateway{Gateway: sdktest.NewGateway(srv.Store, sdktest.GatewayAddress), store: srv.Store}
+	client := sdk.NewSaoClientApiWithBackends(gateway, srv.Chain, "alice", home)
+	gateway.rejects, gateway.advance, gateway.err = 1, 1000, errors.New("request rejected")
+	if got := load(t, client, dataId); got != `{"a":1}` || gateway.loads != 2 {
+		t.Fatalf("got %s after %d loads", got, gateway.loads)
+	}
+
+	// a request still valid is not, whatever the gateway answered
+	gateway.loads, gateway.rejects, gateway.advance = 0, 1, 0
+	gateway.err = errors.New("invalid query, LastValidHeight:1 > now:2")
+	if _, err := client.Load(ctx, dataId, "", "", "g"); err == nil || gateway.loads != 1 {
+		t.Fatalf("got %v after %d loads", err, gateway.loads)
+	}
+}
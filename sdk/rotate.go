@@ -0,0 +1,414 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	"golang.org/x/xerrors"
+)
+
+// RotationState is the state of a model in a RotationManifest.
+type RotationState string
+
+const (
+	// RotationPending means the model was not migrated yet.
+	RotationPending RotationState = "pending"
+	// RotationFailed means the last migration of the model failed, it is retried on the next run.
+	RotationFailed RotationState = "failed"
+	// RotationMigrated means the content of the model is encrypted for the new did instead of the old one.
+	RotationMigrated RotationState = "migrated"
+	// RotationSkipped means the content of the model is not encrypted for the old did, so there is nothing to migrate.
+	RotationSkipped RotationState = "skipped"
+)
+
+// RotationEntry records the migration of a single model.
+type RotationEntry struct {
+	DataId string        `json:"dataId"`
+	Alias  string        `json:"alias"`
+	State  RotationState `json:"state"`
+	// CommitId is the commit holding the content encrypted for the new did.
+	CommitId string `json:"commitId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RotationManifest records the progress of a key rotation in a local file, so that an interrupted rotation
+// can be resumed, see RotateOptions.Manifest.
+type RotationManifest struct {
+	GroupId string          `json:"groupId"`
+	OldDid  string          `json:"oldDid"`
+	NewDid  string          `json:"newDid"`
+	Models  []RotationEntry `json:"models"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// LoadRotationManifest reads the manifest at path, or returns an empty one to be saved there if it does not exist.
+func LoadRotationManifest(path string) (*RotationManifest, error) {
+	m := &RotationManifest{path: path}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	err = json.Unmarshal(content, m)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrUnMarshalFailed, "invalid rotation manifest %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Entry returns the entry of the model with the given data id.
+func (m *RotationManifest) Entry(dataId string) (RotationEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(dataId)
+	if i < 0 {
+		return RotationEntry{}, false
+	}
+	return m.Models[i], true
+}
+
+// Migrated returns the data ids of the models migrated to the new did.
+func (m *RotationManifest) Migrated() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var dataIds []string
+	for _, entry := range m.Models {
+		if entry.State == RotationMigrated {
+			dataIds = append(dataIds, entry.DataId)
+		}
+	}
+	return dataIds
+}
+
+// Pending returns the entries of the models still to migrate, including the failed ones.
+func (m *RotationManifest) Pending() []RotationEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []RotationEntry
+	for _, entry := range m.Models {
+		if !entry.done() {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Save writes the manifest back to the file it was loaded from, it does nothing for a manifest without file.
+func (m *RotationManifest) Save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.save()
+}
+
+func (m *RotationManifest) save() error {
+	if m.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return writeAtomic(m.path, func(w io.Writer) error {
+		_, err := w.Write(content)
+		if err != nil {
+			return types.Wrap(types.ErrWriteFileFailed, err)
+		}
+		return nil
+	})
+}
+
+func (m *RotationManifest) index(dataId string) int {
+	for i, entry := range m.Models {
+		if entry.DataId == dataId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (e RotationEntry) done() bool {
+	return e.State == RotationMigrated || e.State == RotationSkipped
+}
+
+// prepare binds the manifest to the rotation and adds the models not recorded yet. It returns the entries
+// of the models which still need to be migrated.
+func (m *RotationManifest) prepare(groupId string, oldDid string, newDid string, models []RotationEntry) ([]RotationEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.OldDid == "" && m.NewDid == "" {
+		m.GroupId, m.OldDid, m.NewDid = groupId, oldDid, newDid
+	}
+	if m.GroupId != groupId || m.OldDid != oldDid || m.NewDid != newDid {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "rotation manifest %s belongs to the rotation of group %s from %s to %s",
+			m.path, m.GroupId, m.OldDid, m.NewDid)
+	}
+
+	var pending []RotationEntry
+	for _, model := range models {
+		i := m.index(model.DataId)
+		if i < 0 {
+			m.Models = append(m.Models, model)
+			i = len(m.Models) - 1
+		}
+		if !m.Models[i].done() {
+			pending = append(pending, m.Models[i])
+		}
+	}
+	return pending, m.save()
+}
+
+// record stores the outcome of a migration and saves the manifest.
+func (m *RotationManifest) record(entry RotationEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.index(entry.DataId)
+	if i < 0 {
+		return nil
+	}
+	m.Models[i] = entry
+	return m.save()
+}
+
+// RotateOptions describe a key rotation, see RotateKeys. Zero Duration and Replica keep the ones of each model,
+// a zero Delay takes the default.
+type RotateOptions struct {
+	GroupId string
+	// OldKeyName is the key the content is encrypted for, the client key if empty.
+	OldKeyName string
+	// NewKeyName is the key the content is encrypted for after the rotation.
+	NewKeyName string
+	// Manifest is the path of the RotationManifest of the rotation, the models it records as migrated
+	// are not migrated again. Without Manifest, the progress is only kept in memory.
+	Manifest string
+	// Progress is called after every model.
+	Progress func(RotationProgress)
+	// ContinueOnError migrates the remaining models after a failure.
+	ContinueOnError bool
+	// Duration is how long the data is stored, it is converted into blocks with the chain block time.
+	Duration time.Duration
+	// Delay is the number of epochs the gateway has to complete the orders.
+	Delay Epochs
+	// Replica is the number of storage replicas.
+	Replica uint64
+}
+
+// RotationProgress reports the migration of a model.
+type RotationProgress struct {
+	DataId string
+	State  RotationState
+	Err    error
+	// Done is the number of models migrated or skipped so far, including the ones of earlier runs, out of Total.
+	Done  int
+	Total int
+}
+
+// RotateKeys re-encrypts the models of the client in a group from the did of the old key to the did of the new key:
+// the latest commit of every model whose content is encrypted for the old did is loaded, decrypted with the old key,
+// encrypted again under a new content key for the new did and the other recipients, and written back with a forced
+// update replacing that commit. The new did is also granted the write permission on the migrated models.
+// Earlier commits stay encrypted for the old did. It stops at the first failure unless RotateOptions.ContinueOnError
+// is set, and returns an error if any model failed, along with the manifest recording the state of every model.
+func (sc *SaoClientApi) RotateKeys(ctx context.Context, opts RotateOptions) (*RotationManifest, error) {
+	if opts.NewKeyName == "" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "must provide the new key name")
+	}
+	oldKeyName := opts.OldKeyName
+	if oldKeyName == "" {
+		oldKeyName = sc.KeyName(ctx)
+	}
+
+	owner, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+	oldDid, _, err := sc.GetDidManager(ctx, oldKeyName)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+	newDid, _, err := sc.GetDidManager(ctx, opts.NewKeyName)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+	if oldDid.Id == newDid.Id {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "%s and %s have the same did", oldKeyName, opts.NewKeyName)
+	}
+
+	manifest := &RotationManifest{}
+	if opts.Manifest != "" {
+		manifest, err = LoadRotationManifest(opts.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		// a dry run skips the migrated models but does not record its progress
+		if dryRunFrom(ctx) != nil {
+			manifest.path = ""
+		}
+	}
+
+	metas, err := sc.client.ListMetaByDid(ctx, owner.Id)
+	if err != nil {
+		return nil, err
+	}
+	var models []RotationEntry
+	for _, meta := range metas {
+		if meta.GroupId == opts.GroupId {
+			models = append(models, RotationEntry{DataId: meta.DataId, Alias: meta.Alias, State: RotationPending})
+		}
+	}
+
+	pending, err := manifest.prepare(opts.GroupId, oldDid.Id, newDid.Id, models)
+	if err != nil {
+		return nil, err
+	}
+
+	done := len(models) - len(pending)
+	var failed error
+	for _, entry := range pending {
+		if ctx.Err() != nil {
+			return manifest, ctx.Err()
+		}
+
+		entry.CommitId, entry.State, err = sc.rotateModel(ctx, entry.DataId, oldKeyName, newDid.Id, opts)
+		entry.Error = ""
+		if err != nil {
+			entry.State, entry.Error = RotationFailed, err.Error()
+			if failed == nil {
+				failed = xerrors.Errorf("failed to rotate the key of %s: %w", entry.DataId, err)
+			}
+		} else {
+			done++
+		}
+
+		recordErr := manifest.record(entry)
+		if opts.Progress != nil {
+			opts.Progress(RotationProgress{DataId: entry.DataId, State: entry.State, Err: err, Done: done, Total: len(models)})
+		}
+		if recordErr != nil {
+			return manifest, recordErr
+		}
+		if failed != nil && !opts.ContinueOnError {
+			break
+		}
+	}
+	return manifest, failed
+}
+
+// rotateModel migrates the latest commit of a model from the did of oldKeyName to newDid, and returns the commit
+// holding the migrated content.
+func (sc *SaoClientApi) rotateModel(ctx context.Context, dataId string, oldKeyName string, newDid string, opts RotateOptions) (string, RotationState, error) {
+	oldDid, _, err := sc.GetDidManager(ctx, oldKeyName)
+	if err != nil {
+		return "", "", xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	resp, err := sc.loadResponse(ctx, dataId, "", "", opts.GroupId)
+	if err != nil {
+		return "", "", err
+	}
+	if !IsEncrypted(resp.Content) {
+		return "", RotationSkipped, nil
+	}
+	envelope, err := ParseEnvelope(resp.Content)
+	if err != nil {
+		return "", "", err
+	}
+
+	recipients := make(map[string]bool)
+	for _, did := range envelope.Dids() {
+		recipients[did] = true
+	}
+	if !recipients[oldDid.Id] {
+		// migrated by an interrupted run which could not record it
+		if recipients[newDid] {
+			return resp.CommitId, RotationMigrated, sc.grantRotated(ctx, dataId, newDid)
+		}
+		return "", RotationSkipped, nil
+	}
+
+	cek, err := sc.contentKey(ctx, oldKeyName, envelope)
+	if err != nil {
+		return "", "", err
+	}
+	plaintext, err := envelope.open(cek)
+	if err != nil {
+		return "", "", err
+	}
+
+	dids := []string{newDid}
+	for _, did := range envelope.Dids() {
+		if did != oldDid.Id {
+			dids = append(dids, did)
+		}
+	}
+	content, err := sealEnvelope(plaintext, dids)
+	if err != nil {
+		return "", "", err
+	}
+
+	patch, targetCid, size, err := sc.PatchGen(string(resp.Content), string(content))
+	if err != nil {
+		return "", "", types.Wrap(types.ErrCreatePatchFailed, err)
+	}
+	_, _, commitId, err := sc.UpdateModelWithRequest(ctx, UpdateModelRequest{
+		Keyword:     dataId,
+		GroupId:     opts.GroupId,
+		CommitId:    resp.CommitId,
+		Patch:       patch,
+		Cid:         targetCid.String(),
+		Size:        uint64(size),
+		Force:       true,
+		Duration:    opts.Duration,
+		Delay:       opts.Delay,
+		Replica:     opts.Replica,
+		KeepStorage: true,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return commitId, RotationMigrated, sc.grantRotated(ctx, dataId, newDid)
+}
+
+// grantRotated grants newDid the write permission on a migrated model, keeping the other permissions.
+func (sc *SaoClientApi) grantRotated(ctx context.Context, dataId string, newDid string) error {
+	meta, err := sc.client.GetMeta(ctx, dataId)
+	if err != nil {
+		return err
+	}
+	metadata := meta.Metadata
+	if metadata.Owner == newDid {
+		return nil
+	}
+	for _, did := range metadata.ReadwriteDids {
+		if did == newDid {
+			return nil
+		}
+	}
+
+	var readonlyDids []string
+	for _, did := range metadata.ReadonlyDids {
+		if did != newDid {
+			readonlyDids = append(readonlyDids, did)
+		}
+	}
+	signed, err := sc.BuildPermissionProposal(ctx, dataId, readonlyDids, append(metadata.ReadwriteDids, newDid))
+	if err != nil {
+		return err
+	}
+	_, err = sc.client.ModelUpdatePermission(ctx, signed.Permission, true)
+	return err
+}
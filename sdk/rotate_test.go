@@ -0,0 +1,111 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestRotateKeys(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	alice := newClient(t, srv, home, "alice")
+	rotated := newClient(t, srv, home, "alice2")
+	bob := newClient(t, srv, home, "bob")
+	bobDid := didOf(t, bob, "bob")
+
+	// the last model is not encrypted, so not migrated
+	var dataIds []string
+	for i := 0; i < 4; i++ {
+		_, dataId, err := alice.CreateModelWithRequest(ctx, sdk.CreateModelRequest{
+			Content:    fmt.Sprintf(`{"n":%d}`, i),
+			GroupId:    "g",
+			Name:       fmt.Sprint("m", i),
+			Duration:   48 * time.Hour,
+			Replica:    1,
+			Encrypt:    i != 3,
+			Recipients: []string{bobDid},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dataIds = append(dataIds, dataId)
+	}
+	if err := alice.UpdatePermission(ctx, dataIds[0], []string{bobDid}, nil); err != nil {
+		t.Fatal(err)
+	}
+	before, err := srv.Chain.GetMeta(ctx, dataIds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first run is interrupted after the first model
+	manifest := filepath.Join(t.TempDir(), "rotation.json")
+	cctx, cancel := context.WithCancel(ctx)
+	m, err := alice.RotateKeys(cctx, sdk.RotateOptions{GroupId: "g", NewKeyName: "alice2", Manifest: manifest, Progress: func(sdk.RotationProgress) {
+		cancel()
+	}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if len(m.Migrated()) != 1 || len(m.Pending()) != 3 {
+		t.Fatalf("got %d migrated and %d pending models", len(m.Migrated()), len(m.Pending()))
+	}
+
+	var migrated []string
+	m, err = alice.RotateKeys(ctx, sdk.RotateOptions{GroupId: "g", NewKeyName: "alice2", Manifest: manifest, Progress: func(p sdk.RotationProgress) {
+		migrated = append(migrated, p.DataId)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 3 || len(m.Migrated()) != 3 || len(m.Pending()) != 0 {
+		t.Fatalf("got %d migrated in this run, %d in all and %d pending models", len(migrated), len(m.Migrated()), len(m.Pending()))
+	}
+	if entry, _ := m.Entry(dataIds[3]); entry.State != sdk.RotationSkipped {
+		t.Fatalf("got %s for the plain model, want %s", entry.State, sdk.RotationSkipped)
+	}
+
+	for i, dataId := range dataIds[:3] {
+		if got := load(t, rotated, dataId); got != fmt.Sprintf(`{"n":%d}`, i) {
+			t.Fatalf("got %s", got)
+		}
+		if _, err := alice.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrPermissionDenied) {
+			t.Fatalf("got %v, want %v", err, sdk.ErrPermissionDenied)
+		}
+	}
+	// the other recipients and the storage terms are kept
+	if got := load(t, bob, dataIds[0]); got != `{"n":0}` {
+		t.Fatalf("got %s", got)
+	}
+	after, err := srv.Chain.GetMeta(ctx, dataIds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.OrderId == before.OrderId {
+		t.Fatal("the rotation placed no new order")
+	}
+	for _, orderId := range []uint64{before.OrderId, after.OrderId} {
+		order, err := srv.Chain.GetOrder(ctx, orderId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.Duration != 48*3600 || order.Replica != 1 {
+			t.Fatalf("order %d: got %d blocks and %d replicas, want %d and 1", orderId, order.Duration, order.Replica, 48*3600)
+		}
+	}
+
+	_, err = alice.RotateKeys(ctx, sdk.RotateOptions{GroupId: "g", NewKeyName: "bob", Manifest: manifest})
+	if !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+}
@@ -5,9 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -17,7 +14,6 @@ import (
 	api "github.com/SaoNetwork/sao-node/api"
 	apitypes "github.com/SaoNetwork/sao-node/api/types"
 	"github.com/SaoNetwork/sao-node/chain"
-	saoclient "github.com/SaoNetwork/sao-node/client"
 	types "github.com/SaoNetwork/sao-node/types"
 	utils "github.com/SaoNetwork/sao-node/utils"
 	modeltypes "github.com/SaoNetwork/sao/x/model/types"
@@ -51,73 +47,276 @@ type SaoClientApi struct {
 	ChainEndpoint string
 	Closer        func()
 	keyName       string
-	keyringHome   string
+	signer        Signer
+	transportHome string
+	transport     string
+	fileTransport FileTransport
+	log           Logger
+	dids          *didCache
+	blockTime     time.Duration
+	retry         RetryPolicy
+	pool          *GatewayPool
+	verifyContent bool
+	cache         Cache
+	observed      *observedBlockTime
+	// offline is set for the clients of NewOfflineSaoClientApi.
+	offline bool
 }
 
-func NewSaoClientApi(ctx context.Context, nodeEndpoint string, chainEndpoint string, KeyName string, keyringHome string) (*SaoClientApi, error) {
-	client, closer, err := NewSaoClient(ctx, nodeEndpoint, chainEndpoint)
+func NewSaoClientApi(ctx context.Context, nodeEndpoint string, chainEndpoint string, KeyName string, keyringHome string, opts ...Option) (*SaoClientApi, error) {
+	cfg := DefaultConfig()
+	cfg.NodeEndpoint = nodeEndpoint
+	cfg.ChainEndpoint = chainEndpoint
+	cfg.KeyName = KeyName
+	cfg.KeyringHome = keyringHome
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return NewSaoClientApiWithConfig(ctx, cfg)
+}
+
+// NewSaoClientApiWithConfig creates a SaoClientApi connected to the gateway and the chain given in cfg,
+// see LoadConfig to read it from a file and the environment.
+func NewSaoClientApiWithConfig(ctx context.Context, cfg Config) (*SaoClientApi, error) {
+	err := cfg.Validate()
 	if err != nil {
 		return nil, err
 	}
 
+	client, closer, err := newSaoClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSaoClientApi(cfg, client, closer), nil
+}
+
+// NewSaoClientApiWithBackends creates a SaoClientApi on top of the given gateway and chain
+// implementations instead of dialing real endpoints. With a RequestTimeout, a GatewayPool is replaced by
+// a pool over the same gateways bounding each of them, which keeps its own health state.
+func NewSaoClientApiWithBackends(gateway GatewayApi, chainApi ChainApi, keyName string, keyringHome string, opts ...Option) *SaoClientApi {
+	cfg := DefaultConfig()
+	cfg.KeyName = keyName
+	cfg.KeyringHome = keyringHome
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client := &SaoClient{
+		GatewayApi: gateway,
+		ChainApi:   chainApi,
+	}
+	return newSaoClientApi(cfg, client, func() {})
+}
+
+func newSaoClientApi(cfg Config, client *SaoClient, closer func()) *SaoClientApi {
+	pool, _ := client.GatewayApi.(*GatewayPool)
+	if cfg.RequestTimeout > 0 {
+		var gatewayApi GatewayApi
+		if pool != nil {
+			// the gateways of a pool are bounded one by one, so a timeout does not prevent the failover.
+			pool = pool.withTimeout(cfg.RequestTimeout)
+			gatewayApi = pool
+		} else {
+			gatewayApi = &timeoutGateway{GatewayApi: client.GatewayApi, timeout: cfg.RequestTimeout}
+		}
+		client = &SaoClient{
+			GatewayApi: gatewayApi,
+			ChainApi:   &timeoutChain{ChainApi: client.ChainApi, timeout: cfg.RequestTimeout},
+		}
+	}
+	client = &SaoClient{
+		GatewayApi: &errorGateway{GatewayApi: client.GatewayApi},
+		ChainApi:   &errorChain{ChainApi: client.ChainApi},
+	}
+	if cfg.Retry.attempts() > 1 {
+		client = &SaoClient{
+			GatewayApi: &retryGateway{GatewayApi: client.GatewayApi, policy: cfg.Retry},
+			ChainApi:   &retryChain{ChainApi: client.ChainApi, policy: cfg.Retry},
+		}
+	}
+	client = &SaoClient{
+		GatewayApi: &dryRunGateway{GatewayApi: client.GatewayApi},
+		ChainApi:   client.ChainApi,
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = DefaultConfig().Logger
+	}
+	if cfg.FileTransport == nil {
+		cfg.FileTransport = &libp2pTransport{home: cfg.TransportHome}
+	}
+	if cfg.Signer == nil && cfg.SignerEndpoint != "" {
+		cfg.Signer = NewRemoteSigner(cfg.SignerEndpoint, cfg.SignerToken)
+	}
+	if cfg.Signer == nil {
+		cfg.Signer = NewKeyringSigner(cfg.KeyringHome)
+	}
+
 	return &SaoClientApi{
-		NodeEndpoint:  nodeEndpoint,
-		ChainEndpoint: chainEndpoint,
+		NodeEndpoint:  cfg.NodeEndpoint,
+		ChainEndpoint: cfg.ChainEndpoint,
 		Closer:        closer,
 		client:        client,
-		keyName:       KeyName,
-		keyringHome:   keyringHome,
-	}, nil
+		keyName:       cfg.KeyName,
+		signer:        cfg.Signer,
+		dids:          &didCache{},
+		observed:      &observedBlockTime{},
+		transportHome: cfg.TransportHome,
+		transport:     cfg.Transport,
+		fileTransport: cfg.FileTransport,
+		log:           cfg.Logger,
+		blockTime:     cfg.BlockTime,
+		retry:         cfg.Retry,
+		pool:          pool,
+		verifyContent: cfg.VerifyContent,
+		cache:         cfg.Cache,
+	}
 }
 
 type SaoClient struct {
-	api.SaoApi
-	chain.ChainSvcApi
+	GatewayApi
+	ChainApi
 }
 
 func NewSaoClient(ctx context.Context, nodeEndpoint string, chainEndpoint string) (*SaoClient, func(), error) {
-	var gatewayApi api.SaoApi = nil
-	var closer = func() {}
-	var err error
-	gatewayApi, closer, err = NewNodeApi(ctx, nodeEndpoint, "default token")
+	cfg := DefaultConfig()
+	cfg.NodeEndpoint = nodeEndpoint
+	cfg.ChainEndpoint = chainEndpoint
+	return newSaoClient(ctx, cfg)
+}
+
+func newSaoClient(ctx context.Context, cfg Config) (*SaoClient, func(), error) {
+	gatewayApi, closer, err := newGatewayApi(ctx, cfg)
 	if err != nil {
 		return nil, nil, err
 	}
-	chainSvc, err := chain.NewChainSvc(ctx, chainEndpoint, "/websocket", "~/.sao")
+
+	dialCtx := ctx
+	if cfg.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, cfg.DialTimeout)
+		defer cancel()
+	}
+	chainSvc, err := chain.NewChainSvc(dialCtx, cfg.ChainEndpoint, cfg.ChainWsPath, cfg.ChainHome)
 	if err != nil {
+		closer()
 		return nil, nil, err
 	}
 	return &SaoClient{
-		SaoApi:      gatewayApi,
-		ChainSvcApi: chainSvc,
+		GatewayApi: gatewayApi,
+		ChainApi:   chainSvc,
 	}, closer, nil
 }
 
+// newGatewayApi connects the gateway, or a GatewayPool if several node endpoints are configured.
+// Endpoints which can not be connected are left out of the pool.
+func newGatewayApi(ctx context.Context, cfg Config) (GatewayApi, func(), error) {
+	endpoints := cfg.nodeEndpoints()
+	if len(endpoints) == 1 {
+		return NewNodeApi(ctx, endpoints[0], cfg.GatewayToken)
+	}
+
+	log := cfg.Logger
+	if log == nil {
+		log = DefaultConfig().Logger
+	}
+
+	var gateways []GatewayApi
+	var closers []jsonrpc.ClientCloser
+	var lastErr error
+	for _, endpoint := range endpoints {
+		gatewayApi, closer, err := NewNodeApi(ctx, endpoint, cfg.GatewayToken)
+		if err != nil {
+			log.Warnf("failed to connect gateway %s: %v", endpoint, err)
+			lastErr = err
+			continue
+		}
+
+		gateways = append(gateways, gatewayApi)
+		closers = append(closers, closer)
+	}
+	if len(gateways) == 0 {
+		return nil, nil, lastErr
+	}
+
+	closer := func() {
+		for _, closer := range closers {
+			closer()
+		}
+	}
+	return NewGatewayPool(gateways, cfg.HealthCheckInterval, log), closer, nil
+}
+
+// pinGateway returns the gateway the next call goes to with its address. With a GatewayPool, it also returns
+// the pinned gateway of the pool, to report it if it turns out to be unavailable. The pinned gateway is decorated
+// like the client gateway, except for the failover.
+func (sc *SaoClientApi) pinGateway(ctx context.Context) (GatewayApi, GatewayApi, string, error) {
+	if sc.pool == nil {
+		address, err := sc.client.GetNodeAddress(ctx)
+		return sc.client.GatewayApi, nil, address, err
+	}
+
+	pinned, address, err := sc.pool.pin(ctx)
+	if err != nil {
+		return nil, nil, "", classifyError("GetNodeAddress", err)
+	}
+	var gateway GatewayApi = &errorGateway{GatewayApi: pinned}
+	if sc.retry.attempts() > 1 {
+		gateway = &retryGateway{GatewayApi: gateway, policy: sc.retry}
+	}
+	return &dryRunGateway{GatewayApi: gateway}, pinned, address, nil
+}
+
+// GetDidManager returns the authenticated did manager of the given key and its account address.
+// The did is derived once per key name and cached until InvalidateDidManager is called.
 func (sc *SaoClientApi) GetDidManager(ctx context.Context, keyName string) (*saodid.DidManager, string, error) {
-	address, err := chain.GetAddress(ctx, sc.keyringHome, keyName)
+	id, err := sc.dids.get(ctx, keyName, sc.deriveIdentity)
 	if err != nil {
 		return nil, "", err
 	}
+	return id.didManager, id.address, nil
+}
+
+// InvalidateDidManager drops the cached did manager of the given key, e.g. after the key was replaced in the keyring.
+func (sc *SaoClientApi) InvalidateDidManager(keyName string) {
+	sc.dids.invalidate(keyName)
+}
+
+// InvalidateDidManagers drops all cached did managers.
+func (sc *SaoClientApi) InvalidateDidManagers() {
+	sc.dids.invalidateAll()
+}
+
+func (sc *SaoClientApi) deriveIdentity(ctx context.Context, keyName string) (*identity, error) {
+	address, err := sc.signer.GetAddress(ctx, keyName)
+	if err != nil {
+		return nil, err
+	}
 
 	payload := fmt.Sprintf("cosmos %s allows to generate did", address)
-	secret, err := chain.SignByAccount(ctx, sc.keyringHome, keyName, []byte(payload))
+	secret, err := sc.signer.Sign(ctx, keyName, []byte(payload))
 	if err != nil {
-		return nil, "", types.Wrap(types.ErrSignedFailed, err)
+		return nil, types.Wrap(types.ErrSignedFailed, err)
 	}
 
 	provider, err := saokey.NewSecp256k1Provider(secret)
 	if err != nil {
-		return nil, "", types.Wrap(types.ErrCreateProviderFailed, err)
+		return nil, types.Wrap(types.ErrCreateProviderFailed, err)
 	}
 	resolver := saokey.NewKeyResolver()
 
 	didManager := saodid.NewDidManager(provider, resolver)
 	_, err = didManager.Authenticate([]string{}, "")
 	if err != nil {
-		return nil, "", types.Wrap(types.ErrAuthenticateFailed, err)
+		return nil, types.Wrap(types.ErrAuthenticateFailed, err)
 	}
 
-	return &didManager, address, nil
+	return &identity{
+		didManager: &didManager,
+		address:    address,
+		secret:     secret,
+	}, nil
 }
 
 func (sc *SaoClientApi) Renew(
@@ -125,60 +324,62 @@ func (sc *SaoClientApi) Renew(
 	dataIds []string,
 	duration uint64,
 	delay uint64,
-) (map[string]uint64, map[string]string, map[string]string, error) {
-	if len(dataIds) <= 0 {
-		return nil, nil, nil, xerrors.Errorf("data ids is missing.")
-	}
-
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+) ([]RenewResult, error) {
+	d, err := daysToDuration(duration)
 	if err != nil {
-		return nil, nil, nil, xerrors.Errorf("failed to get did manager %v", err)
-	}
-
-	proposal := saotypes.RenewProposal{
-		Owner:    didManager.Id,
-		Duration: uint64(time.Duration(60*60*24*duration) * time.Second / chain.Blocktime),
-		Timeout:  int32(delay),
-		Data:     dataIds,
+		return nil, err
 	}
+	return sc.RenewWithRequest(ctx, RenewRequest{
+		DataIds:  dataIds,
+		Duration: d,
+		Delay:    Epochs(delay),
+	})
+}
 
-	proposalBytes, err := proposal.Marshal()
+// RenewWithRequest validates req, filling in the default storage parameters, and submits it.
+// It returns one result per requested data id, in the order of req.DataIds.
+func (sc *SaoClientApi) RenewWithRequest(
+	ctx context.Context,
+	req RenewRequest,
+) ([]RenewResult, error) {
+	signed, err := sc.BuildRenewProposal(ctx, req, ProposalParams{})
 	if err != nil {
-		return nil, nil, nil, types.Wrap(types.ErrMarshalFailed, err)
+		return nil, err
 	}
 
-	jws, err := didManager.CreateJWS(proposalBytes)
+	res, err := sc.client.ModelRenewOrder(ctx, signed.Renew, true)
 	if err != nil {
-		return nil, nil, nil, types.Wrap(types.ErrCreateJwsFailed, err)
-	}
-	clientProposal := types.OrderRenewProposal{
-		Proposal:     proposal,
-		JwsSignature: saotypes.JwsSignature(jws.Signatures[0]),
+		return nil, err
 	}
+	return sc.renewResults(ctx, req.DataIds, res.Results), nil
+}
 
-	var results map[string]string
-	res, err := sc.client.ModelRenewOrder(ctx, &clientProposal, true)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	results = res.Results
+// renewResults returns one result per renewed data id from the results returned by the gateway.
+func (sc *SaoClientApi) renewResults(ctx context.Context, dataIds []string, messages map[string]string) []RenewResult {
+	results := make([]RenewResult, 0, len(dataIds))
+	for _, dataId := range dataIds {
+		message, found := messages[dataId]
+		if !found {
+			results = append(results, RenewResult{
+				DataId:  dataId,
+				Status:  RenewStatusFailed,
+				Message: "no result returned by the gateway",
+			})
+			continue
+		}
 
-	var renewModels = make(map[string]uint64, len(results))
-	var renewedOrders = make(map[string]string, 0)
-	var failedOrders = make(map[string]string, 0)
-	for dataId, result := range results {
-		if strings.Contains(result, "SUCCESS") {
-			orderId, err := strconv.ParseUint(strings.Split(result, "=")[1], 10, 64)
+		result := ParseRenewResult(dataId, message)
+		if result.Status == RenewStatusRenewed && dryRunFrom(ctx) == nil {
+			meta, err := sc.client.GetMeta(ctx, dataId)
 			if err != nil {
-				failedOrders[dataId] = result + ", " + err.Error()
+				sc.log.Warnf("failed to get the expire height of %s: %v", dataId, err)
 			} else {
-				renewModels[dataId] = orderId
+				result.ExpireHeight = meta.Metadata.CreatedAt + meta.Metadata.Duration
 			}
-		} else {
-			renewedOrders[dataId] = result
 		}
+		results = append(results, result)
 	}
-	return renewModels, renewedOrders, failedOrders, nil
+	return results
 }
 
 func (sc *SaoClientApi) ShowCommits(
@@ -187,12 +388,12 @@ func (sc *SaoClientApi) ShowCommits(
 	groupId string,
 ) (*apitypes.ShowCommitsResp, error) {
 	if keyword == "" {
-		return nil, xerrors.Errorf("keyword is missing.")
+		return nil, types.Wrapf(types.ErrInvalidParameters, "keyword is missing")
 	}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get did manager %v", err)
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
 	}
 
 	proposal := saotypes.QueryProposal{
@@ -205,17 +406,11 @@ func (sc *SaoClientApi) ShowCommits(
 		proposal.KeywordType = 2
 	}
 
-	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	request, err := sc.buildQueryRequest(ctx, didManager, proposal, sc.client, gatewayAddress)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := sc.client.ModelShowCommits(ctx, request)
+	var resp apitypes.ShowCommitsResp
+	err = sc.withQueryRequest(ctx, didManager, proposal, true, func(gateway GatewayApi, _ string, request *types.MetadataProposal) error {
+		resp, err = gateway.ModelShowCommits(ctx, request)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -227,34 +422,15 @@ func (sc *SaoClientApi) Delete(
 	dataId string,
 ) (string, error) {
 	if dataId == "" {
-		return "", xerrors.Errorf("dataId is missing")
+		return "", types.Wrapf(types.ErrInvalidParameters, "dataId is missing")
 	}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	signed, err := sc.BuildTerminateProposal(ctx, dataId)
 	if err != nil {
-		return "", xerrors.Errorf("failed to get did manager %v", err)
-	}
-
-	proposal := saotypes.TerminateProposal{
-		Owner:  didManager.Id,
-		DataId: dataId,
-	}
-
-	proposalBytes, err := proposal.Marshal()
-	if err != nil {
-		return "", types.Wrap(types.ErrMarshalFailed, err)
-	}
-
-	jws, err := didManager.CreateJWS(proposalBytes)
-	if err != nil {
-		return "", types.Wrap(types.ErrCreateJwsFailed, err)
-	}
-	request := types.OrderTerminateProposal{
-		Proposal:     proposal,
-		JwsSignature: saotypes.JwsSignature(jws.Signatures[0]),
+		return "", err
 	}
 
-	result, err := sc.client.ModelDelete(ctx, &request, true)
+	result, err := sc.client.ModelDelete(ctx, signed.Terminate, true)
 	if err != nil {
 		return "", err
 	}
@@ -272,54 +448,116 @@ func (sc *SaoClientApi) Load(
 	if err != nil {
 		return nil, err
 	}
-	return resp.Content, nil
+	return sc.decryptLoaded(ctx, resp.Content)
 }
 
+// UpdatePermission grants the dids the permission to read or to write the model with the given data id. The dids
+// are not added to the recipients of encrypted content, see UpdatePermissionWithRequest.
 func (sc *SaoClientApi) UpdatePermission(
 	ctx context.Context,
 	dataId string,
 	readonlyDids []string,
 	readwriteDids []string,
 ) error {
-	if dataId == "" {
-		return xerrors.Errorf("data id is missing")
+	return sc.UpdatePermissionWithRequest(ctx, UpdatePermissionRequest{
+		DataId:        dataId,
+		ReadonlyDids:  readonlyDids,
+		ReadwriteDids: readwriteDids,
+	})
+}
+
+// UpdatePermissionWithRequest grants the permission of the request. With Encrypted, the dids are first added to
+// the recipients of the content with an update, and the permission is granted once the update succeeded: if the
+// grant fails then, the dids are recipients without permission, and the request can be retried as is, the dids
+// which are recipients already are not added again.
+func (sc *SaoClientApi) UpdatePermissionWithRequest(ctx context.Context, req UpdatePermissionRequest) error {
+	err := req.Validate()
+	if err != nil {
+		return err
 	}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	if req.Encrypted {
+		share, err := sc.shareEncrypted(ctx, req.DataId, append(append([]string{}, req.ReadonlyDids...), req.ReadwriteDids...))
+		if err != nil {
+			return err
+		}
+		if share != nil {
+			_, _, _, err = sc.UpdateModelWithRequest(ctx, *share)
+			if err != nil {
+				return xerrors.Errorf("failed to add the recipients to the encrypted content of %s, no permission granted: %w", req.DataId, err)
+			}
+		}
+	}
+
+	signed, err := sc.BuildPermissionProposal(ctx, req.DataId, req.ReadonlyDids, req.ReadwriteDids)
 	if err != nil {
-		return xerrors.Errorf("failed to get did manager %v", err)
+		return err
 	}
 
-	proposal := saotypes.PermissionProposal{
-		Owner:         didManager.Id,
-		DataId:        dataId,
-		ReadonlyDids:  readonlyDids,
-		ReadwriteDids: readwriteDids,
+	_, err = sc.client.ModelUpdatePermission(ctx, signed.Permission, true)
+	if err != nil {
+		if req.Encrypted {
+			return xerrors.Errorf("the recipients of %s were added but the permission was not granted: %w", req.DataId, err)
+		}
+		return err
 	}
+	return nil
+}
 
-	proposalBytes, err := proposal.Marshal()
+// shareEncrypted returns the update adding dids to the recipients of the encrypted model content, keeping the
+// storage terms of the model, or nil if the dids are recipients already.
+func (sc *SaoClientApi) shareEncrypted(ctx context.Context, dataId string, dids []string) (*UpdateModelRequest, error) {
+	// a model created by the same dry run is not on the chain to load its content from.
+	if d := dryRunFrom(ctx); d != nil && d.created(dataId) {
+		return nil, nil
+	}
+	meta, err := sc.client.GetMeta(ctx, dataId)
 	if err != nil {
-		return types.Wrap(types.ErrMarshalFailed, err)
+		return nil, err
 	}
+	groupId := meta.Metadata.GroupId
 
-	jws, err := didManager.CreateJWS(proposalBytes)
+	resp, err := sc.loadResponse(ctx, dataId, "", "", groupId)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(resp.Content) {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "the content of %s is not encrypted", dataId)
+	}
+	envelope, err := ParseEnvelope(resp.Content)
 	if err != nil {
-		return types.Wrap(types.ErrCreateJwsFailed, err)
+		return nil, err
+	}
+	recipients := make(map[string]bool)
+	for _, did := range envelope.Dids() {
+		recipients[did] = true
+	}
+	missing := false
+	for _, did := range dids {
+		missing = missing || !recipients[did]
+	}
+	if !missing {
+		return nil, nil
 	}
 
-	request := &types.PermissionProposal{
-		Proposal: proposal,
-		JwsSignature: saotypes.JwsSignature{
-			Protected: jws.Signatures[0].Protected,
-			Signature: jws.Signatures[0].Signature,
-		},
+	content, err := sc.AddRecipients(ctx, resp.Content, dids...)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = sc.client.ModelUpdatePermission(ctx, request, true)
+	patch, targetCid, size, err := sc.PatchGen(string(resp.Content), string(content))
 	if err != nil {
-		return err
+		return nil, types.Wrap(types.ErrCreatePatchFailed, err)
 	}
-	return nil
+	return &UpdateModelRequest{
+		Keyword:     dataId,
+		GroupId:     groupId,
+		CommitId:    resp.CommitId,
+		Patch:       patch,
+		Cid:         targetCid.String(),
+		Size:        uint64(size),
+		KeepStorage: true,
+	}, nil
 }
 
 func (sc *SaoClientApi) SetPublicPermission(ctx context.Context, dataId string) error {
@@ -401,77 +639,104 @@ func (sc *SaoClientApi) UpdateModel(
 	replica uint64,
 	groupId string,
 ) (string, string, string, error) {
-	if keyword == "" {
-		return "", "", "", xerrors.Errorf("must provide keyword.")
-	}
-	if size == 0 {
-		return "", "", "", xerrors.Errorf("invalid size")
-	}
-	newCid, err := cid.Decode(cidstring)
+	d, err := daysToDuration(duration)
 	if err != nil {
-		return "", "", "", xerrors.Errorf("invalid cid: %v", cidstring)
+		return "", "", "", err
 	}
+	return sc.UpdateModelWithRequest(ctx, UpdateModelRequest{
+		Keyword:  keyword,
+		GroupId:  groupId,
+		CommitId: commitId,
+		Patch:    patch,
+		Cid:      cidstring,
+		Size:     size,
+		Force:    force,
+		Duration: d,
+		Delay:    Epochs(delay),
+		Replica:  replica,
+	})
+}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+// UpdateModelWithRequest validates req, filling in the default storage parameters, and submits it.
+func (sc *SaoClientApi) UpdateModelWithRequest(ctx context.Context, req UpdateModelRequest) (string, string, string, error) {
+	return sc.updateModel(ctx, req, nil)
+}
+
+// updateModel updates a model, loaded is the content at req.CommitId if the caller already loaded it.
+func (sc *SaoClientApi) updateModel(ctx context.Context, req UpdateModelRequest, loaded *apitypes.LoadResp) (string, string, string, error) {
+	err := req.Validate()
 	if err != nil {
-		return "", "", "", xerrors.Errorf("failed to get did manager %v", err)
+		return "", "", "", err
+	}
+
+	if req.Encrypt {
+		if loaded == nil {
+			loaded, err = sc.loadResponse(ctx, req.Keyword, "", req.CommitId, req.GroupId)
+			if err != nil {
+				return "", "", "", xerrors.Errorf("failed to load sao data: %w", err)
+			}
+		}
+		err = sc.sealPatch(ctx, &req, loaded.Content)
+		if err != nil {
+			return "", "", "", err
+		}
 	}
 
-	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
 	if err != nil {
-		return "", "", "", err
+		return "", "", "", xerrors.Errorf("failed to get did manager: %w", err)
 	}
 
 	queryProposal := saotypes.QueryProposal{
 		Owner:   didManager.Id,
-		Keyword: keyword,
-		GroupId: groupId,
+		Keyword: req.Keyword,
+		GroupId: req.GroupId,
 	}
 
-	if !utils.IsDataId(keyword) {
+	if !utils.IsDataId(req.Keyword) {
 		queryProposal.KeywordType = 2
 	}
 
-	request, err := sc.buildQueryRequest(ctx, didManager, queryProposal, sc.client, gatewayAddress)
-	if err != nil {
-		return "", "", "", err
-	}
-
-	res, err := sc.client.QueryMetadata(ctx, request, 0)
-	if err != nil {
-		return "", "", "", err
+	var blocks uint64
+	if req.Duration > 0 {
+		blocks, err = sc.storageBlocks(ctx, req.Duration)
+		if err != nil {
+			return "", "", "", err
+		}
 	}
 
-	operation := uint32(1)
+	var resp apitypes.UpdateResp
+	err = sc.withQueryRequest(ctx, didManager, queryProposal, false, func(gateway GatewayApi, gatewayAddress string, request *types.MetadataProposal) error {
+		res, err := sc.client.QueryMetadata(ctx, request, 0)
+		if err != nil {
+			return err
+		}
 
-	if force {
-		operation = 2
-	}
+		duration, replica := blocks, int32(req.Replica)
+		if duration == 0 {
+			duration = res.Metadata.Duration
+		}
+		if replica == 0 {
+			replica = res.Metadata.Replica
+		}
+		if duration == 0 || replica == 0 {
+			return types.Wrapf(types.ErrInvalidParameters, "no storage terms to keep for %s", res.Metadata.DataId)
+		}
 
-	proposal := saotypes.Proposal{
-		Owner:      didManager.Id,
-		Provider:   gatewayAddress,
-		GroupId:    groupId,
-		Duration:   uint64(time.Duration(60*60*24*duration) * time.Second / chain.Blocktime),
-		Replica:    int32(replica),
-		Timeout:    int32(delay),
-		DataId:     res.Metadata.DataId,
-		Alias:      res.Metadata.Alias,
-		Tags:       []string{},
-		Cid:        newCid.String(),
-		CommitId:   commitId + "|" + utils.GenerateCommitId(didManager.Id+groupId),
-		Rule:       "",
-		Operation:  operation,
-		Size_:      uint64(size),
-		ExtendInfo: "",
-	}
+		proposal, err := updateModelProposal(didManager, req, res.Metadata.DataId, res.Metadata.Alias, duration, replica)
+		if err != nil {
+			return err
+		}
+		proposal.Provider = gatewayAddress
 
-	clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
-	if err != nil {
-		return "", "", "", err
-	}
+		clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
+		if err != nil {
+			return err
+		}
 
-	resp, err := sc.client.ModelUpdate(ctx, request, clientProposal, 0, []byte(patch))
+		resp, err = gateway.ModelUpdate(ctx, request, clientProposal, 0, []byte(req.Patch))
+		return err
+	})
 	if err != nil {
 		return "", "", "", err
 	}
@@ -491,24 +756,51 @@ func (sc *SaoClientApi) UpdateModelQuick(
 	// Load the existing content
 	resp, err := sc.loadResponse(ctx, dataId, "", "", groupId)
 	if err != nil {
-		return fmt.Errorf("Failed to load sao data: %v", err)
+		return xerrors.Errorf("failed to load sao data: %w", err)
+	}
+
+	// The patch of an encrypted model is generated between the decrypted contents
+	origin := resp.Content
+	encrypted := IsEncrypted(origin)
+	if encrypted {
+		origin, err = sc.DecryptContent(ctx, origin)
+		if err != nil {
+			return xerrors.Errorf("failed to decrypt sao data: %w", err)
+		}
 	}
 
 	// Generate a patch between the old content and the target content
-	patch, targetCid, size, err := sc.PatchGen(string(resp.Content), string(jsonData))
+	patch, targetCid, size, err := sc.PatchGen(string(origin), string(jsonData))
 	if err != nil {
-		return fmt.Errorf("Error generating patch: %v", err)
+		return xerrors.Errorf("failed to generate patch: %w", err)
 	}
 
 	if patch == "[]" || patch == "" {
 		// No differences found, return an error
-		return fmt.Errorf("No differences found, unable to update model")
+		return xerrors.Errorf("no differences found, unable to update model: %w", ErrNoChanges)
+	}
+
+	d, err := daysToDuration(duration)
+	if err != nil {
+		return err
 	}
 
 	// Update the model using the generated patch
-	_, _, _, err = sc.UpdateModel(ctx, patch, duration, delay, force, dataId, resp.CommitId, targetCid.String(), uint64(size), replica, groupId)
+	_, _, _, err = sc.updateModel(ctx, UpdateModelRequest{
+		Keyword:  dataId,
+		GroupId:  groupId,
+		CommitId: resp.CommitId,
+		Patch:    patch,
+		Cid:      targetCid.String(),
+		Size:     uint64(size),
+		Force:    force,
+		Duration: d,
+		Delay:    Epochs(delay),
+		Replica:  replica,
+		Encrypt:  encrypted,
+	}, resp)
 	if err != nil {
-		return fmt.Errorf("Error updating model: %v", err)
+		return xerrors.Errorf("failed to update model: %w", err)
 	}
 
 	return nil
@@ -520,48 +812,21 @@ func (sc *SaoClientApi) UploadFile(
 	multiaddr string,
 	protocol string,
 ) ([]string, error) {
-	if protocol == "" {
-		protocol = "udp"
-	}
-
-	if !strings.Contains(multiaddr, "/p2p/") {
-		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid multiaddr: %s", multiaddr)
-	}
-	peerId := strings.Split(multiaddr, "/p2p/")[1]
-
-	var files []string
-	err := filepath.Walk(fpath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() {
-			files = append(files, path)
-		} else {
-			fmt.Printf("skip directory %s\r\n", path)
-		}
-
-		return nil
-	})
+	return sc.UploadFileWithOptions(ctx, fpath, multiaddr, UploadOptions{Protocol: protocol})
+}
 
+// UploadFileWithOptions uploads the file, or the files under the directory, at fpath and returns their cids.
+// It stops at the first failure, see UploadDir to keep going.
+func (sc *SaoClientApi) UploadFileWithOptions(ctx context.Context, fpath string, multiaddr string, opts UploadOptions) ([]string, error) {
+	opts.ContinueOnError = false
+	results, err := sc.UploadDir(ctx, fpath, multiaddr, opts)
 	if err != nil {
-		return nil, types.Wrap(types.ErrInvalidParameters, err)
+		return nil, err
 	}
 
 	var cids []string
-	for _, file := range files {
-		var c cid.Cid
-		if protocol == "tcp" {
-			c = saoclient.DoTransportTCP(ctx, "~/.sao-cli", multiaddr, file)
-		} else {
-			c = saoclient.DoTransport(ctx, "~/.sao-cli", multiaddr, peerId, file)
-		}
-
-		if c != cid.Undef {
-			cids = append(cids, c.String())
-		} else {
-			return nil, xerrors.Errorf("upload error")
-		}
+	for _, result := range results {
+		cids = append(cids, result.Cid)
 	}
 	return cids, nil
 }
@@ -576,45 +841,34 @@ func (sc *SaoClientApi) CreateFile(
 	replicas uint64,
 	size uint64,
 ) (string, string, error) {
-	if fileName == "" {
-		return "", "", xerrors.Errorf("must provide file name")
-	}
-
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	d, err := daysToDuration(duration)
 	if err != nil {
-		return "", "", xerrors.Errorf("failed to get did manager %v", err)
+		return "", "", err
 	}
+	return sc.CreateFileWithRequest(ctx, CreateFileRequest{
+		FileName: fileName,
+		Cid:      cidString,
+		GroupId:  groupId,
+		Size:     size,
+		Duration: d,
+		Delay:    Epochs(delay),
+		Replica:  replicas,
+	})
+}
 
-	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
+// CreateFileWithRequest validates req, filling in the default storage parameters, and submits it.
+func (sc *SaoClientApi) CreateFileWithRequest(ctx context.Context, req CreateFileRequest) (string, string, error) {
+	err := req.Validate()
 	if err != nil {
 		return "", "", err
 	}
 
-	contentCid, err := cid.Decode(cidString)
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
 	if err != nil {
-		return "", "", types.Wrap(types.ErrInvalidCid, err)
+		return "", "", xerrors.Errorf("failed to get did manager: %w", err)
 	}
 
-	dataId := utils.GenerateDataId(didManager.Id + groupId)
-	proposal := saotypes.Proposal{
-		DataId:     dataId,
-		Owner:      didManager.Id,
-		Provider:   gatewayAddress,
-		GroupId:    groupId,
-		Duration:   uint64(time.Duration(60*60*24*duration) * time.Second / chain.Blocktime),
-		Replica:    int32(replicas),
-		Timeout:    int32(delay),
-		Alias:      fileName,
-		Tags:       []string{},
-		Cid:        contentCid.String(),
-		CommitId:   dataId,
-		Rule:       "",
-		Operation:  1,
-		ExtendInfo: "",
-		Size_:      size,
-	}
-
-	clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
+	proposal, err := sc.createFileProposal(ctx, didManager, req, 0)
 	if err != nil {
 		return "", "", err
 	}
@@ -623,15 +877,20 @@ func (sc *SaoClientApi) CreateFile(
 
 	queryProposal := saotypes.QueryProposal{
 		Owner:   didManager.Id,
-		Keyword: dataId,
+		Keyword: proposal.DataId,
 	}
 
-	request, err := sc.buildQueryRequest(ctx, didManager, queryProposal, sc.client, gatewayAddress)
-	if err != nil {
-		return "", "", err
-	}
+	var resp apitypes.CreateResp
+	err = sc.withQueryRequest(ctx, didManager, queryProposal, false, func(gateway GatewayApi, gatewayAddress string, request *types.MetadataProposal) error {
+		proposal.Provider = gatewayAddress
+		clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
+		if err != nil {
+			return err
+		}
 
-	resp, err := sc.client.ModelCreateFile(ctx, request, clientProposal, orderId)
+		resp, err = gateway.ModelCreateFile(ctx, request, clientProposal, orderId)
+		return err
+	})
 	if err != nil {
 		return "", "", err
 	}
@@ -648,67 +907,59 @@ func (sc *SaoClientApi) CreateModel(
 	replicas uint64,
 	isPublic bool,
 ) (string, string, error) {
-	if content == "" {
-		return "", "", xerrors.Errorf("must provide content")
+	d, err := daysToDuration(duration)
+	if err != nil {
+		return "", "", err
 	}
+	return sc.CreateModelWithRequest(ctx, CreateModelRequest{
+		Content:  content,
+		GroupId:  groupId,
+		Name:     name,
+		IsPublic: isPublic,
+		Duration: d,
+		Delay:    Epochs(delay),
+		Replica:  replicas,
+	})
+}
 
-	contentBytes := []byte(content)
-	contentCid, err := CalculateCid(contentBytes)
+// CreateModelWithRequest validates req, filling in the default storage parameters, and submits it.
+func (sc *SaoClientApi) CreateModelWithRequest(ctx context.Context, req CreateModelRequest) (string, string, error) {
+	err := req.Validate()
 	if err != nil {
 		return "", "", err
 	}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
 	if err != nil {
-		return "", "", xerrors.Errorf("failed to get did manager %v", err)
+		return "", "", xerrors.Errorf("failed to get did manager: %w", err)
 	}
 
-	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
+	proposal, contentBytes, err := sc.createModelProposal(ctx, didManager, req, 0)
 	if err != nil {
 		return "", "", err
 	}
 
-	dataId := utils.GenerateDataId(didManager.Id + groupId)
-	proposal := saotypes.Proposal{
-		DataId:     dataId,
-		Owner:      didManager.Id,
-		Provider:   gatewayAddress,
-		GroupId:    groupId,
-		Duration:   uint64(time.Duration(60*60*24*duration) * time.Second / chain.Blocktime),
-		Replica:    int32(replicas),
-		Timeout:    int32(delay),
-		Alias:      name,
-		Tags:       []string{""},
-		Cid:        contentCid.String(),
-		CommitId:   dataId,
-		Rule:       "",
-		Size_:      uint64(len(content)),
-		Operation:  1,
-		ExtendInfo: "",
-	}
-	if proposal.Alias == "" {
-		proposal.Alias = proposal.Cid
-	}
 	queryProposal := saotypes.QueryProposal{
 		Owner:   didManager.Id,
-		Keyword: dataId,
-	}
-	clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
-	if err != nil {
-		return "", "", err
+		Keyword: proposal.DataId,
 	}
 
-	request, err := sc.buildQueryRequest(ctx, didManager, queryProposal, sc.client, gatewayAddress)
-	if err != nil {
-		return "", "", err
-	}
+	var resp apitypes.CreateResp
+	err = sc.withQueryRequest(ctx, didManager, queryProposal, false, func(gateway GatewayApi, gatewayAddress string, request *types.MetadataProposal) error {
+		proposal.Provider = gatewayAddress
+		clientProposal, err := sc.buildClientProposal(ctx, didManager, proposal, sc.client)
+		if err != nil {
+			return err
+		}
 
-	resp, err := sc.client.ModelCreate(ctx, request, clientProposal, 0, contentBytes)
+		resp, err = gateway.ModelCreate(ctx, request, clientProposal, 0, contentBytes)
+		return err
+	})
 	if err != nil {
 		return "", "", err
 	}
 
-	if isPublic {
+	if req.IsPublic {
 		err := sc.SetPublicPermission(ctx, resp.DataId)
 		if err != nil {
 			return "", "", err
@@ -726,12 +977,12 @@ func (sc *SaoClientApi) buildQueryRequest(
 	ctx context.Context,
 	didManager *did.DidManager,
 	proposal saotypes.QueryProposal,
-	chain chain.ChainSvcApi,
+	chain ChainApi,
 	gatewayAddress string,
 ) (*types.MetadataProposal, error) {
 	lastHeight, err := chain.GetLastHeight(ctx)
 	if err != nil {
-		return nil, types.Wrap(types.ErrQueryHeightFailed, err)
+		return nil, xerrors.Errorf("failed to query the latest height: %w", err)
 	}
 
 	peerInfo, err := chain.GetNodePeer(ctx, gatewayAddress)
@@ -739,8 +990,14 @@ func (sc *SaoClientApi) buildQueryRequest(
 		return nil, err
 	}
 
-	proposal.LastValidHeight = uint64(lastHeight + 200)
-	proposal.Gateway = peerInfo
+	return signQueryRequest(didManager, proposal, peerInfo, uint64(lastHeight)+queryValidBlocks)
+}
+
+// signQueryRequest signs proposal as a query request addressed to the gateway with the given peer info,
+// valid up to lastValidHeight.
+func signQueryRequest(didManager *did.DidManager, proposal saotypes.QueryProposal, gatewayPeer string, lastValidHeight uint64) (*types.MetadataProposal, error) {
+	proposal.LastValidHeight = lastValidHeight
+	proposal.Gateway = gatewayPeer
 
 	if proposal.Owner == "all" {
 		return &types.MetadataProposal{
@@ -767,7 +1024,7 @@ func (sc *SaoClientApi) buildQueryRequest(
 	}, nil
 }
 
-func (sc *SaoClientApi) buildClientProposal(_ context.Context, didManager *did.DidManager, proposal saotypes.Proposal, _ chain.ChainSvcApi) (*types.OrderStoreProposal, error) {
+func (sc *SaoClientApi) buildClientProposal(_ context.Context, didManager *did.DidManager, proposal saotypes.Proposal, _ ChainApi) (*types.OrderStoreProposal, error) {
 	proposalBytes, err := proposal.Marshal()
 	if err != nil {
 		return nil, types.Wrap(types.ErrMarshalFailed, err)
@@ -794,15 +1051,15 @@ func (sc *SaoClientApi) loadResponse(
 	groupId string,
 ) (*apitypes.LoadResp, error) { // Replace ResponseType with the actual type of resp
 	if keyword == "" {
-		return nil, xerrors.Errorf("keyword is missing")
+		return nil, types.Wrapf(types.ErrInvalidParameters, "keyword is missing")
 	}
 	if version != "" && commitId != "" {
 		version = ""
 	}
 
-	didManager, _, err := sc.GetDidManager(ctx, sc.keyName)
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
 	if err != nil {
-		return nil, xerrors.Errorf("failed to get did manager %v", err)
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
 	}
 
 	proposal := saotypes.QueryProposal{
@@ -817,19 +1074,37 @@ func (sc *SaoClientApi) loadResponse(
 		proposal.KeywordType = 2
 	}
 
-	gatewayAddress, err := sc.client.GetNodeAddress(ctx)
+	var resp apitypes.LoadResp
+	cached := false
+	err = sc.withQueryRequest(ctx, didManager, proposal, true, func(gateway GatewayApi, _ string, request *types.MetadataProposal) error {
+		if sc.cache != nil {
+			hit, err := sc.cachedLoad(ctx, request)
+			if err != nil {
+				return err
+			}
+			if hit != nil {
+				resp, cached = *hit, true
+				return nil
+			}
+		}
+		resp, err = gateway.ModelLoad(ctx, request)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	request, err := sc.buildQueryRequest(ctx, didManager, proposal, sc.client, gatewayAddress)
-	if err != nil {
-		return nil, err
+	if cached {
+		return &resp, nil
 	}
 
-	resp, err := sc.client.ModelLoad(ctx, request)
-	if err != nil {
-		return nil, err
+	if sc.verifyContent {
+		err = sc.verifyLoaded(ctx, &resp, keyword, version, commitId, groupId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sc.cache != nil {
+		sc.cachePut(didManager.Id, &resp)
 	}
 	return &resp, nil
 }
@@ -845,7 +1120,7 @@ func CalculateCid(content []byte) (cid.Cid, error) {
 
 	contentCid, err := pref.Sum(content)
 	if err != nil {
-		return cid.Undef, xerrors.Errorf("")
+		return cid.Undef, types.Wrap(types.ErrCalculateCidFailed, err)
 	}
 
 	return contentCid, nil
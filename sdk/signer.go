@@ -0,0 +1,107 @@
+package sdk
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Signer holds the account keys of the client. It supplies the address of a key name, and signs the payload
+// the did of the key is generated from, see GetDidManager. Implementations must be safe for concurrent use.
+type Signer interface {
+	GetAddress(ctx context.Context, keyName string) (string, error)
+	Sign(ctx context.Context, keyName string, payload []byte) ([]byte, error)
+}
+
+// KeyringSigner signs with the keys of the file keyring in a directory, see Config.KeyringHome.
+type KeyringSigner struct {
+	home string
+}
+
+var _ Signer = (*KeyringSigner)(nil)
+
+func NewKeyringSigner(home string) *KeyringSigner {
+	return &KeyringSigner{home: home}
+}
+
+func (s *KeyringSigner) GetAddress(ctx context.Context, keyName string) (string, error) {
+	return chain.GetAddress(ctx, s.home, keyName)
+}
+
+func (s *KeyringSigner) Sign(ctx context.Context, keyName string, payload []byte) ([]byte, error) {
+	return chain.SignByAccount(ctx, s.home, keyName, payload)
+}
+
+// MemorySigner signs with secp256k1 keys held in memory, it produces the same addresses and signatures
+// as the keyring for the same keys.
+type MemorySigner struct {
+	mu   sync.RWMutex
+	keys map[string]*secp256k1.PrivKey
+}
+
+var _ Signer = (*MemorySigner)(nil)
+
+func NewMemorySigner() *MemorySigner {
+	return &MemorySigner{keys: make(map[string]*secp256k1.PrivKey)}
+}
+
+// AddKey adds the 32 bytes secp256k1 private key under keyName, replacing the key of that name if any.
+func (s *MemorySigner) AddKey(keyName string, key []byte) error {
+	if len(key) != secp256k1.PrivKeySize {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid private key length %d, expect %d", len(key), secp256k1.PrivKeySize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[keyName] = &secp256k1.PrivKey{Key: append([]byte{}, key...)}
+	return nil
+}
+
+// AddMnemonic adds the key derived from the mnemonic under keyName, on the path the keyring derives
+// its accounts on, so the key has the address of the account imported from the same mnemonic.
+func (s *MemorySigner) AddMnemonic(keyName string, mnemonic string) error {
+	path := hd.CreateHDPath(sdktypes.GetConfig().GetCoinType(), 0, 0).String()
+	key, err := hd.Secp256k1.Derive()(mnemonic, "", path)
+	if err != nil {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid mnemonic: %v", err)
+	}
+	return s.AddKey(keyName, key)
+}
+
+func (s *MemorySigner) GetAddress(ctx context.Context, keyName string) (string, error) {
+	key, found := s.key(keyName)
+	if !found {
+		return "", types.Wrapf(types.ErrGetAddressFailed, "key %s not found", keyName)
+	}
+	address, err := sdktypes.Bech32ifyAddressBytes(chain.ADDRESS_PREFIX, key.PubKey().Address())
+	if err != nil {
+		return "", types.Wrap(types.ErrGetAddressFailed, err)
+	}
+	return address, nil
+}
+
+func (s *MemorySigner) Sign(ctx context.Context, keyName string, payload []byte) ([]byte, error) {
+	key, found := s.key(keyName)
+	if !found {
+		return nil, types.Wrapf(types.ErrSignedFailed, "key %s not found", keyName)
+	}
+	sig, err := key.Sign(payload)
+	if err != nil {
+		return nil, types.Wrap(types.ErrSignedFailed, err)
+	}
+	return sig, nil
+}
+
+func (s *MemorySigner) key(keyName string) (*secp256k1.PrivKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, found := s.keys[keyName]
+	return key, found
+}
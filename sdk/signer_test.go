@@ -0,0 +1,140 @@
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// memorySignerOf creates a key in the keyring under home and adds it to a MemorySigner by its mnemonic.
+func memorySignerOf(t *testing.T, home string, keyName string) (*sdk.MemorySigner, string) {
+	t.Helper()
+
+	_, address, mnemonic, err := chain.Create(context.Background(), home, keyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := sdk.NewMemorySigner()
+	if err := signer.AddMnemonic(keyName, mnemonic); err != nil {
+		t.Fatal(err)
+	}
+	return signer, address
+}
+
+func TestMemorySignerMatchesKeyring(t *testing.T) {
+	ctx := context.Background()
+	home := t.TempDir()
+	memory, address := memorySignerOf(t, home, "alice")
+	keyring := sdk.NewKeyringSigner(home)
+
+	got, err := memory.GetAddress(ctx, "alice")
+	if err != nil || got != address {
+		t.Fatalf("got %s, %v, want %s", got, err, address)
+	}
+	want, err := keyring.Sign(ctx, "alice", []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := memory.Sign(ctx, "alice", []byte("payload"))
+	if err != nil || !bytes.Equal(sig, want) {
+		t.Fatalf("got %x, %v, want %x", sig, err, want)
+	}
+
+	if err := memory.AddMnemonic("bob", "not a mnemonic"); err == nil {
+		t.Fatal("added an invalid mnemonic")
+	}
+	if _, err := memory.GetAddress(ctx, "bob"); !errors.Is(err, types.ErrGetAddressFailed) {
+		t.Fatalf("got %v, want %v", err, types.ErrGetAddressFailed)
+	}
+	if _, err := memory.Sign(ctx, "bob", []byte("payload")); !errors.Is(err, types.ErrSignedFailed) {
+		t.Fatalf("got %v, want %v", err, types.ErrSignedFailed)
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	ctx := context.Background()
+	memory, address := memorySignerOf(t, t.TempDir(), "alice")
+	server := httptest.NewServer(sdk.NewSignerHandler(memory, "token"))
+	defer server.Close()
+
+	remote := sdk.NewRemoteSigner(server.URL+"/", "token")
+	got, err := remote.GetAddress(ctx, "alice")
+	if err != nil || got != address {
+		t.Fatalf("got %s, %v, want %s", got, err, address)
+	}
+	want, err := memory.Sign(ctx, "alice", []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := remote.Sign(ctx, "alice", []byte("payload"))
+	if err != nil || !bytes.Equal(sig, want) {
+		t.Fatalf("got %x, %v, want %x", sig, err, want)
+	}
+
+	// the failures are reported with the status and the reason
+	cases := []struct {
+		name    string
+		signer  *sdk.RemoteSigner
+		keyName string
+		reason  string
+	}{
+		{"wrong token", sdk.NewRemoteSigner(server.URL, "other"), "alice", "401 Unauthorized: invalid token"},
+		{"no token", sdk.NewRemoteSigner(server.URL, ""), "alice", "401 Unauthorized: invalid token"},
+		{"unknown key", remote, "bob", "key bob not found"},
+		{"no service", sdk.NewRemoteSigner(server.URL+"/missing", "token"), "alice", "404 Not Found"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.signer.GetAddress(ctx, c.keyName)
+			if !errors.Is(err, types.ErrGetAddressFailed) || !strings.Contains(err.Error(), c.reason) {
+				t.Fatalf("got %v, want %v with %q", err, types.ErrGetAddressFailed, c.reason)
+			}
+			_, err = c.signer.Sign(ctx, c.keyName, []byte("payload"))
+			if !errors.Is(err, types.ErrSignedFailed) || !strings.Contains(err.Error(), c.reason) {
+				t.Fatalf("got %v, want %v with %q", err, types.ErrSignedFailed, c.reason)
+			}
+		})
+	}
+
+	resp, err := http.Get(server.URL + "/address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("got %s, want %d", resp.Status, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestClientWithRemoteSigner(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	memory, _ := memorySignerOf(t, home, "alice")
+	server := httptest.NewServer(sdk.NewSignerHandler(memory, "token"))
+	defer server.Close()
+
+	// the remote key is the same account as the keyring one, so it reads the keyring client's models
+	alice, err := srv.NewClient(ctx, "alice", home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataId := createModel(t, alice, `{"a":1}`, "m")
+	remote, err := srv.NewClient(ctx, "alice", t.TempDir(), sdk.WithSigner(sdk.NewRemoteSigner(server.URL, "token")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := load(t, remote, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"context"
+	"time"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// timeoutGateway bounds every gateway call by a fixed timeout.
+type timeoutGateway struct {
+	GatewayApi
+	timeout time.Duration
+}
+
+func (g *timeoutGateway) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelCreate(ctx, req, orderProposal, orderId, content)
+}
+
+func (g *timeoutGateway) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelCreateFile(ctx, req, orderProposal, orderId)
+}
+
+func (g *timeoutGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelLoad(ctx, req)
+}
+
+func (g *timeoutGateway) ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelDelete(ctx, req, isPublish)
+}
+
+func (g *timeoutGateway) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelShowCommits(ctx, req)
+}
+
+func (g *timeoutGateway) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelUpdate(ctx, req, orderProposal, orderId, patch)
+}
+
+func (g *timeoutGateway) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelRenewOrder(ctx, req, isPublish)
+}
+
+func (g *timeoutGateway) ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.ModelUpdatePermission(ctx, req, isPublish)
+}
+
+func (g *timeoutGateway) GetNodeAddress(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	return g.GatewayApi.GetNodeAddress(ctx)
+}
+
+// timeoutChain bounds every chain call by a fixed timeout.
+type timeoutChain struct {
+	ChainApi
+	timeout time.Duration
+}
+
+func (c *timeoutChain) GetLastHeight(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.GetLastHeight(ctx)
+}
+
+func (c *timeoutChain) GetNodePeer(ctx context.Context, creator string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.GetNodePeer(ctx, creator)
+}
+
+func (c *timeoutChain) QueryDidParams(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.QueryDidParams(ctx)
+}
+
+func (c *timeoutChain) QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.QueryMetadata(ctx, req, height)
+}
+
+func (c *timeoutChain) GetModel(ctx context.Context, key string) (*modeltypes.QueryGetModelResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.GetModel(ctx, key)
+}
+
+func (c *timeoutChain) GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.GetMeta(ctx, dataId)
+}
+
+func (c *timeoutChain) ListMetaByDid(ctx context.Context, did string) ([]modeltypes.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.ListMetaByDid(ctx, did)
+}
+
+func (c *timeoutChain) GetBlock(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.ChainApi.GetBlock(ctx, height)
+}
@@ -0,0 +1,300 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	"github.com/libp2p/go-libp2p"
+	ic "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	tpt "github.com/libp2p/go-libp2p/core/transport"
+	libp2pwebtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
+	"github.com/mitchellh/go-homedir"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// FileTransport connects to the file transport of a gateway, which receives the file content before CreateFile.
+type FileTransport interface {
+	// Dial connects to the gateway at multiaddr, which must end with /p2p/<peer id>, over udp or tcp.
+	Dial(ctx context.Context, multiaddr string, protocol string) (FileTransportConn, error)
+}
+
+// FileTransportConn sends the chunks of a file to the gateway.
+type FileTransportConn interface {
+	// SendChunk sends a single chunk and returns the cid the gateway acknowledged it with: the chunk cid,
+	// or the cid of the whole content once the last, empty, chunk completed the file.
+	SendChunk(ctx context.Context, chunk *types.FileChunkReq) (string, error)
+	Close() error
+}
+
+// FileTransportStreamer is implemented by the FileTransportConn which can also send other sao-node rpc requests
+// than the chunks of a file, Download streams the content a gateway loads over it.
+type FileTransportStreamer interface {
+	// Stream sends req, e.g. a Sao.ModelLoad, and returns its response, a JSON encoded types.RpcResp, as it
+	// arrives. The response must be closed.
+	Stream(ctx context.Context, req *types.RpcReq) (io.ReadCloser, error)
+}
+
+// libp2pTransport is the FileTransport of the sao-node gateways, webtransport over udp or a libp2p stream over tcp.
+type libp2pTransport struct {
+	// home holds the transport key, see fetchTransportKey.
+	home string
+}
+
+func (t *libp2pTransport) Dial(ctx context.Context, multiaddr string, protocol string) (FileTransportConn, error) {
+	addr, err := ma.NewMultiaddr(multiaddr)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "%s: %v", multiaddr, err)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(addr)
+	if err != nil {
+		return nil, types.Wrapf(types.ErrInvalidServerAddress, "%s: %v", multiaddr, err)
+	}
+
+	key, err := fetchTransportKey(t.home)
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case "udp":
+		transport, err := libp2pwebtransport.New(key, nil, network.NullResourceManager)
+		if err != nil {
+			return nil, types.Wrap(types.ErrConnectFailed, err)
+		}
+		conn, err := transport.Dial(ctx, addr, info.ID)
+		if err != nil {
+			return nil, types.Wrap(types.ErrConnectFailed, err)
+		}
+		return &webtransportConn{conn: conn}, nil
+	case "tcp":
+		h, err := libp2p.New(libp2p.NoListenAddrs, libp2p.Identity(key))
+		if err != nil {
+			return nil, types.Wrap(types.ErrConnectFailed, err)
+		}
+		err = h.Connect(ctx, *info)
+		if err != nil {
+			h.Close()
+			return nil, types.Wrap(types.ErrConnectFailed, err)
+		}
+		return &hostConn{host: h, peer: info.ID}, nil
+	default:
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid transport %s, expect udp or tcp", protocol)
+	}
+}
+
+var (
+	_ FileTransportStreamer = (*webtransportConn)(nil)
+	_ FileTransportStreamer = (*hostConn)(nil)
+)
+
+type webtransportConn struct {
+	conn tpt.CapableConn
+}
+
+func (c *webtransportConn) SendChunk(ctx context.Context, chunk *types.FileChunkReq) (string, error) {
+	stream, err := c.conn.OpenStream(ctx)
+	if err != nil {
+		return "", types.Wrap(types.ErrCreateStreamFailed, err)
+	}
+	return sendChunk(ctx, stream, chunk)
+}
+
+func (c *webtransportConn) Stream(ctx context.Context, req *types.RpcReq) (io.ReadCloser, error) {
+	stream, err := c.conn.OpenStream(ctx)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateStreamFailed, err)
+	}
+	return openRpc(ctx, stream, req)
+}
+
+func (c *webtransportConn) Close() error {
+	return c.conn.Close()
+}
+
+type hostConn struct {
+	host host.Host
+	peer peer.ID
+}
+
+func (c *hostConn) SendChunk(ctx context.Context, chunk *types.FileChunkReq) (string, error) {
+	stream, err := c.host.NewStream(ctx, c.peer, types.RpcProtocol)
+	if err != nil {
+		return "", types.Wrap(types.ErrCreateStreamFailed, err)
+	}
+	return sendChunk(ctx, stream, chunk)
+}
+
+func (c *hostConn) Stream(ctx context.Context, req *types.RpcReq) (io.ReadCloser, error) {
+	stream, err := c.host.NewStream(ctx, c.peer, types.RpcProtocol)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateStreamFailed, err)
+	}
+	return openRpc(ctx, stream, req)
+}
+
+func (c *hostConn) Close() error {
+	return c.host.Close()
+}
+
+// sendChunk sends chunk as a Sao.Upload request on stream and reads the response. The stream is reset
+// if ctx is done before the response arrived.
+func sendChunk(ctx context.Context, stream network.MuxedStream, chunk *types.FileChunkReq) (string, error) {
+	params, err := json.Marshal(chunk)
+	if err != nil {
+		stream.Close()
+		return "", types.Wrap(types.ErrMarshalFailed, err)
+	}
+	resp, err := openRpc(ctx, stream, &types.RpcReq{
+		Method: "Sao.Upload",
+		Params: []string{string(params)},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Close()
+
+	buf, err := io.ReadAll(resp)
+	if err != nil {
+		return "", ctxOr(ctx, types.Wrap(types.ErrReadResponseFailed, err))
+	}
+
+	var rpcResp types.RpcResp
+	err = json.Unmarshal(buf, &rpcResp)
+	if err != nil {
+		return "", types.Wrap(types.ErrUnMarshalFailed, err)
+	}
+	if rpcResp.Error != "" {
+		return "", types.Wrapf(types.ErrFailuresResponsed, "%s", rpcResp.Error)
+	}
+	return rpcResp.Data, nil
+}
+
+// openRpc sends req on stream and returns the stream to read the response from, which must be closed. The
+// stream is reset if ctx is done before it is closed.
+func openRpc(ctx context.Context, stream network.MuxedStream, req *types.RpcReq) (io.ReadCloser, error) {
+	resp := &rpcStream{stream: stream, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stream.Reset()
+		case <-resp.done:
+		}
+	}()
+
+	buf, err := json.Marshal(req)
+	if err != nil {
+		resp.Close()
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	if _, err := stream.Write(buf); err != nil {
+		resp.Close()
+		return nil, ctxOr(ctx, types.Wrap(types.ErrSendRequestFailed, err))
+	}
+	if err := stream.CloseWrite(); err != nil {
+		resp.Close()
+		return nil, ctxOr(ctx, types.Wrap(types.ErrSendRequestFailed, err))
+	}
+	return resp, nil
+}
+
+// rpcStream is the response side of a stream opened by openRpc.
+type rpcStream struct {
+	stream network.MuxedStream
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *rpcStream) Read(p []byte) (int, error) {
+	return s.stream.Read(p)
+}
+
+func (s *rpcStream) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.stream.Close()
+}
+
+// ctxOr returns the context error if ctx is done, as it caused err, or err otherwise.
+func ctxOr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// selectMultiaddr picks the address of the protocol, udp or tcp, from peers, the comma separated addresses a
+// gateway registers on chain: a webtransport address over udp, or a plain libp2p address over tcp.
+func selectMultiaddr(peers string, protocol string) (string, error) {
+	for _, addr := range strings.Split(peers, ",") {
+		addr = strings.TrimSpace(addr)
+		names := make(map[string]bool)
+		for _, name := range strings.Split(addr, "/") {
+			names[name] = true
+		}
+		if !names["p2p"] {
+			continue
+		}
+		switch protocol {
+		case "udp":
+			if names["udp"] && names["webtransport"] {
+				return addr, nil
+			}
+		case "tcp":
+			if names["tcp"] && !names["ws"] && !names["wss"] {
+				return addr, nil
+			}
+		default:
+			return "", types.Wrapf(types.ErrInvalidParameters, "invalid transport %s, expect udp or tcp", protocol)
+		}
+	}
+	return "", types.Wrapf(types.ErrInvalidServerAddress, "no %s address in %q", protocol, peers)
+}
+
+// fetchTransportKey loads the libp2p key of the file transport from the keystore under home,
+// generating it on first use, like the sao-node client does.
+func fetchTransportKey(home string) (ic.PrivKey, error) {
+	keystore, err := homedir.Expand(filepath.Join(home, "keystore"))
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidRepoPath, err)
+	}
+
+	keyPath := filepath.Join(keystore, "libp2p.key")
+	keyBytes, err := os.ReadFile(keyPath)
+	if err == nil {
+		key, err := ic.UnmarshalPrivateKey(keyBytes)
+		if err != nil {
+			return nil, types.Wrapf(types.ErrReadFileFailed, "invalid transport key %s: %v", keyPath, err)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	err = os.MkdirAll(keystore, 0700)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateDirFailed, err)
+	}
+	key, _, err := ic.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, types.Wrap(types.ErrCreateFileFailed, err)
+	}
+	keyBytes, err = ic.MarshalPrivateKey(key)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	err = os.WriteFile(keyPath, keyBytes, 0600)
+	if err != nil {
+		return nil, types.Wrap(types.ErrWriteFileFailed, err)
+	}
+	return key, nil
+}
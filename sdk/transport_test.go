@@ -0,0 +1,48 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestSelectMultiaddr(t *testing.T) {
+	const (
+		tcp = "/ip4/10.0.0.1/tcp/5153/p2p/12D3KooWGateway"
+		udp = "/ip4/10.0.0.1/udp/5154/quic/webtransport/certhash/uEiA/p2p/12D3KooWGateway"
+		ws  = "/ip4/10.0.0.1/tcp/5155/ws/p2p/12D3KooWGateway"
+	)
+
+	cases := []struct {
+		name     string
+		peers    string
+		protocol string
+		want     string
+		err      error
+	}{
+		{"tcp of several", udp + "," + tcp, "tcp", tcp, nil},
+		{"udp of several", tcp + "," + udp, "udp", udp, nil},
+		{"spaces", tcp + ", " + udp, "udp", udp, nil},
+		{"single", tcp, "tcp", tcp, nil},
+		{"websocket is not tcp", ws + "," + udp, "tcp", "", types.ErrInvalidServerAddress},
+		{"no udp", tcp, "udp", "", types.ErrInvalidServerAddress},
+		{"no peer id", "/ip4/10.0.0.1/tcp/5153", "tcp", "", types.ErrInvalidServerAddress},
+		{"empty", "", "tcp", "", types.ErrInvalidServerAddress},
+		{"unknown protocol", tcp, "quic", "", types.ErrInvalidParameters},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := selectMultiaddr(c.peers, c.protocol)
+			if c.err != nil {
+				if !errors.Is(err, c.err) {
+					t.Fatalf("got %q, %v, want %v", got, err, c.err)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Fatalf("got %q, %v, want %q", got, err, c.want)
+			}
+		})
+	}
+}
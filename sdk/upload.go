@@ -0,0 +1,466 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/SaoNetwork/sao-node/types"
+	cid "github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// UploadProgress reports how much of a file the gateway received.
+type UploadProgress struct {
+	// Path is the uploaded file, empty for UploadReader.
+	Path string
+	// Sent is the number of bytes acknowledged by the gateway so far, out of Total.
+	Sent  int64
+	Total int64
+	// Done is set once the gateway acknowledged the whole content, whose cid is Cid.
+	Done bool
+	Cid  string
+}
+
+// UploadOptions tune UploadFileWithOptions, UploadDir and UploadReader.
+type UploadOptions struct {
+	// Protocol is the file transport protocol, udp or tcp, Config.Transport if empty.
+	Protocol string
+	// Progress is called after every chunk the gateway acknowledged, it should return quickly.
+	// It is called concurrently if several workers upload.
+	Progress func(UploadProgress)
+	// Workers is the number of files uploaded at the same time by UploadDir, 0 means 1.
+	// Every worker holds a chunk of the file it uploads in memory.
+	Workers int
+	// ContinueOnError makes UploadDir upload the remaining files after a failure instead of stopping.
+	ContinueOnError bool
+	// Manifest is the path of an UploadManifest recording the upload, UploadDir skips the files it confirms.
+	Manifest string
+	// Encrypt uploads the files encrypted for the client's did and Recipients, see EncryptContent.
+	// The cid and the size are then those of the envelope, which is built in memory.
+	Encrypt    bool
+	Recipients []string
+}
+
+// UploadReader uploads the content read from r to the gateway at multiaddr and returns its cid. multiaddr may
+// be the comma separated addresses the gateway registered on chain, the one of the protocol is dialed.
+// The transport needs the cid and the size before the first chunk, so r is read twice if it is
+// an io.ReadSeeker, and spooled to a temporary file otherwise.
+func (sc *SaoClientApi) UploadReader(ctx context.Context, r io.Reader, multiaddr string, opts UploadOptions) (string, error) {
+	if opts.Encrypt {
+		content, err := io.ReadAll(&ctxReader{ctx: ctx, r: r})
+		if err != nil {
+			return "", ctxOr(ctx, types.Wrap(types.ErrReadFileFailed, err))
+		}
+		envelope, err := sc.EncryptContent(ctx, content, opts.Recipients...)
+		if err != nil {
+			return "", err
+		}
+		r = bytes.NewReader(envelope)
+	}
+
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		spool, err := os.CreateTemp("", "sao-upload-*")
+		if err != nil {
+			return "", types.Wrap(types.ErrCreateFileFailed, err)
+		}
+		defer os.Remove(spool.Name())
+		defer spool.Close()
+
+		_, err = io.Copy(spool, &ctxReader{ctx: ctx, r: r})
+		if err != nil {
+			return "", ctxOr(ctx, types.Wrap(types.ErrWriteFileFailed, err))
+		}
+		_, err = spool.Seek(0, io.SeekStart)
+		if err != nil {
+			return "", types.Wrap(types.ErrReadFileFailed, err)
+		}
+		rs = spool
+	}
+
+	conn, err := sc.dialTransport(ctx, multiaddr, opts.Protocol)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	c, _, err := sc.upload(ctx, conn, rs, "", opts.Progress)
+	if err != nil {
+		return "", err
+	}
+	return c.String(), nil
+}
+
+func (sc *SaoClientApi) dialTransport(ctx context.Context, multiaddr string, protocol string) (FileTransportConn, error) {
+	if dryRunFrom(ctx) != nil {
+		return dryRunConn{}, nil
+	}
+	if protocol == "" {
+		protocol = sc.transport
+	}
+	multiaddr, err := selectMultiaddr(multiaddr, protocol)
+	if err != nil {
+		return nil, err
+	}
+	return sc.fileTransport.Dial(ctx, multiaddr, protocol)
+}
+
+// uploadPath uploads a single file over conn, encrypted if opts.Encrypt is set, and returns its cid and size.
+func (sc *SaoClientApi) uploadPath(ctx context.Context, conn FileTransportConn, path string, opts UploadOptions) (cid.Cid, int64, error) {
+	if opts.Encrypt {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return cid.Undef, 0, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		envelope, err := sc.EncryptContent(ctx, content, opts.Recipients...)
+		if err != nil {
+			return cid.Undef, 0, err
+		}
+		return sc.upload(ctx, conn, bytes.NewReader(envelope), path, opts.Progress)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return cid.Undef, 0, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	return sc.upload(ctx, conn, file, path, opts.Progress)
+}
+
+// upload computes the cid and the size of the content from its current offset, rewinds it, and sends it
+// chunk by chunk the way the sao-node transport client does. It returns the cid and the size.
+func (sc *SaoClientApi) upload(ctx context.Context, conn FileTransportConn, content io.ReadSeeker, path string, progress func(UploadProgress)) (cid.Cid, int64, error) {
+	start, err := content.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return cid.Undef, 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+	contentCid, length, err := readerCid(ctx, content)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	_, err = content.Seek(start, io.SeekStart)
+	if err != nil {
+		return cid.Undef, 0, types.Wrap(types.ErrReadFileFailed, err)
+	}
+
+	report := func(p UploadProgress) {
+		if progress != nil {
+			p.Path, p.Total = path, length
+			progress(p)
+		}
+	}
+
+	totalChunks := int(length/int64(types.CHUNK_SIZE)) + 1
+	buf := make([]byte, types.CHUNK_SIZE)
+	var sent int64
+	for chunkId := 0; chunkId <= totalChunks; chunkId++ {
+		if ctx.Err() != nil {
+			return cid.Undef, 0, ctx.Err()
+		}
+
+		n, err := io.ReadFull(content, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return cid.Undef, 0, types.Wrap(types.ErrReadFileFailed, err)
+		}
+		chunk := buf[:n]
+		chunkCid, err := CalculateCid(chunk)
+		if err != nil {
+			return cid.Undef, 0, err
+		}
+
+		sc.log.Debugf("send chunk %d of %s, cid %s, length %d", chunkId, contentCid, chunkCid, n)
+		ack, err := conn.SendChunk(ctx, &types.FileChunkReq{
+			ChunkId:     chunkId,
+			TotalLength: int(length),
+			TotalChunks: totalChunks,
+			ChunkCid:    chunkCid.String(),
+			Cid:         contentCid.String(),
+			Content:     chunk,
+		})
+		if err != nil {
+			return cid.Undef, 0, err
+		}
+
+		switch {
+		case n == 0 && ack == contentCid.String():
+			report(UploadProgress{Sent: sent, Done: true, Cid: ack})
+			return contentCid, length, nil
+		case ack == chunkCid.String():
+			sent += int64(n)
+			report(UploadProgress{Sent: sent})
+		default:
+			return cid.Undef, 0, types.Wrapf(types.ErrInvalidCid, "chunk %d acknowledged with cid %s, expected %s", chunkId, ack, chunkCid)
+		}
+	}
+	report(UploadProgress{Sent: sent, Done: true, Cid: contentCid.String()})
+	return contentCid, length, nil
+}
+
+// fileCid computes the cid of the file at path.
+func fileCid(ctx context.Context, path string) (cid.Cid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrOpenFileFailed, err)
+	}
+	defer file.Close()
+
+	c, _, err := readerCid(ctx, file)
+	return c, err
+}
+
+// readerCid computes the same cid as CalculateCid without holding the content in memory, and its size.
+func readerCid(ctx context.Context, r io.Reader) (cid.Cid, int64, error) {
+	hash := sha256.New()
+	n, err := io.Copy(hash, &ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		return cid.Undef, 0, ctxOr(ctx, types.Wrap(types.ErrReadFileFailed, err))
+	}
+	c, err := sha256Cid(hash.Sum(nil))
+	return c, n, err
+}
+
+// sha256Cid returns the cid CalculateCid computes for the content of the given sha256 digest.
+func sha256Cid(digest []byte) (cid.Cid, error) {
+	mh, err := multihash.Encode(digest, multihash.SHA2_256)
+	if err != nil {
+		return cid.Undef, types.Wrap(types.ErrCalculateCidFailed, err)
+	}
+	return cid.NewCidV0(mh), nil
+}
+
+// ctxReader stops reading once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// UploadResult is the outcome of uploading a single file of a directory.
+type UploadResult struct {
+	// Path is the path of the file relative to the uploaded directory, with forward slashes.
+	Path string
+	// Cid is the cid of the file content, empty if the upload failed.
+	Cid  string
+	Size int64
+	// Err is the reason the upload failed, a context error for the files which were not uploaded
+	// because an earlier failure stopped the upload.
+	Err error
+}
+
+type uploadFile struct {
+	path    string
+	rel     string
+	size    int64
+	modTime time.Time
+}
+
+// UploadDir uploads the file, or the files under the directory, at fpath with UploadOptions.Workers concurrent
+// uploads, and returns a result per file in walk order. It stops at the first failure unless
+// UploadOptions.ContinueOnError is set, and returns an error if any file failed, along with all the results.
+// With UploadOptions.Manifest, the files confirmed by an earlier run are not uploaded again.
+func (sc *SaoClientApi) UploadDir(ctx context.Context, fpath string, multiaddr string, opts UploadOptions) ([]UploadResult, error) {
+	if !strings.Contains(multiaddr, "/p2p/") {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid multiaddr: %s", multiaddr)
+	}
+	if opts.Workers < 0 {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid worker count %d", opts.Workers)
+	}
+
+	files, err := sc.listFiles(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := files
+	var manifest *UploadManifest
+	if opts.Manifest != "" {
+		manifest, err = LoadUploadManifest(opts.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		pending, err = manifest.prepare(fpath, files)
+		if err != nil {
+			return nil, err
+		}
+		sc.log.Infof("%d of %d files already uploaded according to %s", len(files)-len(pending), len(files), opts.Manifest)
+	}
+
+	uploaded := make(map[string]UploadResult)
+	if len(pending) > 0 {
+		conn, err := sc.dialTransport(ctx, multiaddr, opts.Protocol)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		for _, result := range sc.uploadFiles(ctx, conn, pending, opts, func(result UploadResult) {
+			if manifest == nil {
+				return
+			}
+			if err := manifest.record(result); err != nil {
+				sc.log.Warnf("failed to save the upload manifest %s: %v", opts.Manifest, err)
+			}
+		}) {
+			uploaded[result.Path] = result
+		}
+		if manifest != nil {
+			if err := manifest.Save(); err != nil {
+				sc.log.Warnf("failed to save the upload manifest %s: %v", opts.Manifest, err)
+			}
+		}
+	}
+
+	results := make([]UploadResult, 0, len(files))
+	for _, file := range files {
+		result, ok := uploaded[file.rel]
+		if !ok && manifest != nil {
+			entry, _ := manifest.Entry(file.rel)
+			result = UploadResult{Path: file.rel, Cid: entry.Cid, Size: entry.ContentSize}
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	var firstErr error
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			if firstErr == nil || errors.Is(firstErr, context.Canceled) {
+				firstErr = result.Err
+			}
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		return results, ctx.Err()
+	case failed == 1 || failed > 0 && !opts.ContinueOnError:
+		return results, firstErr
+	case failed > 0:
+		return results, types.Wrapf(types.ErrStoreFailed, "%d of %d files failed to upload, first: %v", failed, len(results), firstErr)
+	}
+	return results, nil
+}
+
+// listFiles walks fpath and lists the files with their paths relative to it.
+func (sc *SaoClientApi) listFiles(fpath string) ([]uploadFile, error) {
+	var files []uploadFile
+	err := filepath.Walk(fpath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			sc.log.Infof("skip directory %s", path)
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err == nil {
+				info = target
+			}
+		}
+
+		rel, err := filepath.Rel(fpath, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = filepath.Base(path)
+		}
+		files = append(files, uploadFile{
+			path:    path,
+			rel:     filepath.ToSlash(rel),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, types.Wrap(types.ErrInvalidParameters, err)
+	}
+	return files, nil
+}
+
+// uploadFiles uploads files over conn with a pool of workers, calling done with the result of every upload
+// which was started. Unless ContinueOnError is set, the first failure cancels the other uploads.
+func (sc *SaoClientApi) uploadFiles(ctx context.Context, conn FileTransportConn, files []uploadFile, opts UploadOptions, done func(UploadResult)) []UploadResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := opts.Workers
+	if workers == 0 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	results := make([]UploadResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = sc.uploadResult(ctx, conn, files[i], opts)
+				done(results[i])
+				if results[i].Err != nil && !opts.ContinueOnError {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	next := 0
+	for ; next < len(files); next++ {
+		select {
+		case jobs <- next:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(files); i++ {
+		results[i] = UploadResult{Path: files[i].rel, Err: ctx.Err()}
+	}
+	return results
+}
+
+func (sc *SaoClientApi) uploadResult(ctx context.Context, conn FileTransportConn, file uploadFile, opts UploadOptions) UploadResult {
+	result := UploadResult{Path: file.rel}
+	if ctx.Err() != nil {
+		result.Err = ctx.Err()
+		return result
+	}
+
+	c, size, err := sc.uploadPath(ctx, conn, file.path, opts)
+	switch {
+	case err == nil:
+		result.Cid, result.Size = c.String(), size
+	case ctx.Err() != nil:
+		result.Err = ctx.Err()
+	default:
+		sc.log.Warnf("failed to upload %s: %v", file.path, err)
+		result.Err = types.Wrapf(types.ErrStoreFailed, "failed to upload %s: %v", file.path, err)
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package sdk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestUploadFileWithOptions(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	path := filepath.Join(t.TempDir(), "f.txt")
+	writeFile(t, path, "hello world")
+
+	var last sdk.UploadProgress
+	cids, err := client.UploadFileWithOptions(ctx, path, sdktest.GatewayPeerInfo, sdk.UploadOptions{
+		Progress: func(p sdk.UploadProgress) { last = p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := sdk.CalculateCid([]byte("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cids) != 1 || cids[0] != want.String() {
+		t.Fatalf("got %v, want %s", cids, want)
+	}
+	if !last.Done || last.Sent != 11 || last.Total != 11 || last.Cid != want.String() {
+		t.Fatalf("got last progress %+v", last)
+	}
+	if content, ok := srv.Store.Blob(want.String()); !ok || string(content) != "hello world" {
+		t.Fatalf("the gateway has %q, %v", content, ok)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.UploadFileWithOptions(cctx, path, sdktest.GatewayPeerInfo, sdk.UploadOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestUploadReader(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+
+	got, err := client.UploadReader(ctx, strings.NewReader("streamed"), sdktest.GatewayPeerInfo, sdk.UploadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := sdk.CalculateCid([]byte("streamed"))
+	if got != want.String() {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	_, err = client.UploadReader(ctx, strings.NewReader("x"), "/ip4/10.0.0.1/tcp/5153/p2p/12D3KooWGateway", sdk.UploadOptions{})
+	if !errors.Is(err, types.ErrInvalidServerAddress) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidServerAddress)
+	}
+}
+
+func TestUploadDirStopsAtFirstFailure(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a"), "a")
+	if err := os.Symlink("/nonexistent", filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := client.UploadDir(ctx, dir, sdktest.GatewayPeerInfo, sdk.UploadOptions{})
+	if err == nil {
+		t.Fatal("uploaded a dangling link")
+	}
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Fatalf("got %+v", results)
+	}
+}
+
+func TestUploadDirResume(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		writeFile(t, filepath.Join(dir, "sub", fmt.Sprintf("f%d", i)), fmt.Sprint("content", i))
+	}
+	// a dangling link fails to upload until its target exists, the target is outside dir so that no two
+	// workers upload the same content at once
+	target := filepath.Join(t.TempDir(), "target")
+	if err := os.Symlink(target, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+
+	// Progress is called from the upload workers
+	var uploads atomic.Int32
+	opts := sdk.UploadOptions{Workers: 2, ContinueOnError: true, Manifest: manifest, Progress: func(p sdk.UploadProgress) {
+		if p.Done {
+			uploads.Add(1)
+		}
+	}}
+	results, err := client.UploadDir(ctx, dir, sdktest.GatewayPeerInfo, opts)
+	if err == nil {
+		t.Fatal("uploaded a dangling link")
+	}
+	if len(results) != 6 || uploads.Load() != 5 {
+		t.Fatalf("got %d results and %d uploads, want 6 and 5", len(results), uploads.Load())
+	}
+	for _, r := range results {
+		if (r.Err != nil) != (r.Path == "link") {
+			t.Fatalf("%s: got %v", r.Path, r.Err)
+		}
+	}
+
+	writeFile(t, target, "target")
+	uploads.Store(0)
+	results, err = client.UploadDir(ctx, dir, sdktest.GatewayPeerInfo, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 || uploads.Load() != 1 {
+		t.Fatalf("got %d results and %d uploads, want 6 and 1", len(results), uploads.Load())
+	}
+
+	m, err := sdk.LoadUploadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Confirmed()) != 6 || len(m.Pending()) != 0 {
+		t.Fatalf("got %d confirmed and %d pending files", len(m.Confirmed()), len(m.Pending()))
+	}
+	for _, req := range m.CreateFileRequests(sdk.CreateFileRequest{GroupId: "g"}) {
+		if _, _, err := client.CreateFileWithRequest(ctx, req); err != nil {
+			t.Fatalf("%s: %v", req.FileName, err)
+		}
+	}
+}
+
+func TestUploadDirResumeEncrypted(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv, t.TempDir(), "alice")
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeFile(t, filepath.Join(dir, fmt.Sprintf("f%d", i)), fmt.Sprint("secret", i))
+	}
+	manifest := filepath.Join(t.TempDir(), "manifest.json")
+
+	var uploads atomic.Int32
+	opts := sdk.UploadOptions{Workers: 2, Encrypt: true, Manifest: manifest, Progress: func(p sdk.UploadProgress) {
+		if p.Done {
+			uploads.Add(1)
+		}
+	}}
+	for run, want := range []int32{3, 0} {
+		uploads.Store(0)
+		if _, err := client.UploadDir(ctx, dir, sdktest.GatewayPeerInfo, opts); err != nil {
+			t.Fatal(err)
+		}
+		if uploads.Load() != want {
+			t.Fatalf("run %d: got %d uploads, want %d", run, uploads.Load(), want)
+		}
+	}
+
+	m, err := sdk.LoadUploadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range m.Confirmed() {
+		envelope, ok := srv.Store.Blob(entry.Cid)
+		if !ok || !sdk.IsEncrypted(envelope) {
+			t.Fatalf("%s: the gateway has %q", entry.Path, envelope)
+		}
+		if entry.Size != int64(len("secret0")) || entry.ContentSize != int64(len(envelope)) {
+			t.Fatalf("%s: got size %d and content size %d", entry.Path, entry.Size, entry.ContentSize)
+		}
+	}
+	for _, req := range m.CreateFileRequests(sdk.CreateFileRequest{GroupId: "g"}) {
+		if envelope, _ := srv.Store.Blob(req.Cid); req.Size != uint64(len(envelope)) {
+			t.Fatalf("%s: got size %d, want %d", req.FileName, req.Size, len(envelope))
+		}
+	}
+}
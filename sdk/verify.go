@@ -0,0 +1,253 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"golang.org/x/xerrors"
+)
+
+// versionPattern matches the versions of a data, v0 being its first commit.
+var versionPattern = regexp.MustCompile(`^v\d+$`)
+
+// verifyLoaded checks that the gateway loaded the data addressed by keyword, at the requested commit or version,
+// or else at the latest commit on chain, and that the content matches the cid the chain recorded for that commit.
+func (sc *SaoClientApi) verifyLoaded(ctx context.Context, resp *apitypes.LoadResp, keyword string, version string, commitId string, groupId string) error {
+	c, err := CalculateCid(resp.Content)
+	if err != nil {
+		return err
+	}
+	return sc.verifyCommit(ctx, resp, c.String(), keyword, version, commitId, groupId)
+}
+
+// verifyCommit is verifyLoaded for the content of cid c, the content of resp is ignored.
+func (sc *SaoClientApi) verifyCommit(ctx context.Context, resp *apitypes.LoadResp, c string, keyword string, version string, commitId string, groupId string) error {
+	meta, err := sc.expectedCommit(ctx, keyword, version, commitId, groupId)
+	if err != nil {
+		return err
+	}
+	if resp.DataId != meta.DataId || resp.CommitId != meta.Commit {
+		return xerrors.Errorf("%w: the gateway loaded %s commit %s, expected %s commit %s",
+			ErrIntegrity, resp.DataId, resp.CommitId, meta.DataId, meta.Commit)
+	}
+	if c != meta.Cid {
+		return &IntegrityError{DataId: resp.DataId, CommitId: resp.CommitId, Expected: meta.Cid, Actual: c}
+	}
+	return nil
+}
+
+// expectedCommit returns the metadata on chain of the data addressed by keyword as of the given commit, or else
+// of the given version, or else of the latest commit.
+func (sc *SaoClientApi) expectedCommit(ctx context.Context, keyword string, version string, commitId string, groupId string) (*saotypes.Metadata, error) {
+	didManager, _, err := sc.GetDidManager(ctx, sc.KeyName(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to get did manager: %w", err)
+	}
+
+	proposal := saotypes.QueryProposal{
+		Owner:   didManager.Id,
+		Keyword: keyword,
+		GroupId: groupId,
+	}
+	if !utils.IsDataId(keyword) {
+		proposal.KeywordType = 2
+	}
+
+	var meta *saotypes.Metadata
+	err = sc.withQueryRequest(ctx, didManager, proposal, true, func(_ GatewayApi, _ string, request *types.MetadataProposal) error {
+		meta, _, err = sc.commitMetadata(ctx, request, version, commitId)
+		return err
+	})
+	return meta, err
+}
+
+// commitMetadata returns the metadata on chain of the data addressed by request as of the given commit, or else
+// of the given version, or else of the latest commit, along with the version of the commit.
+func (sc *SaoClientApi) commitMetadata(ctx context.Context, request *types.MetadataProposal, version string, commitId string) (*saotypes.Metadata, string, error) {
+	resp, err := sc.client.QueryMetadata(ctx, request, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	commits := resp.Metadata.Commits
+	if len(commits) == 0 {
+		return nil, "", types.Wrapf(types.ErrInvalidCommitInfo, "%s has no commit on chain", resp.Metadata.DataId)
+	}
+
+	index := len(commits) - 1
+	if commitId != "" {
+		index = -1
+		for i, commit := range commits {
+			info, err := types.ParseMetaCommit(commit)
+			if err != nil {
+				return nil, "", err
+			}
+			if info.CommitId == commitId {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, "", types.Wrapf(types.ErrInvalidCommitInfo, "commit %s of %s not found on chain", commitId, resp.Metadata.DataId)
+		}
+	} else if version != "" {
+		index = -1
+		if versionPattern.MatchString(version) {
+			index, _ = strconv.Atoi(version[1:])
+		}
+		if index < 0 || index >= len(commits) {
+			return nil, "", types.Wrapf(types.ErrInvalidVersion, "invalid version %s of %s", version, resp.Metadata.DataId)
+		}
+	}
+
+	info, err := types.ParseMetaCommit(commits[index])
+	if err != nil {
+		return nil, "", err
+	}
+	if info.CommitId != resp.Metadata.Commit {
+		resp, err = sc.client.QueryMetadata(ctx, request, int64(info.Height))
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return &resp.Metadata, fmt.Sprintf("v%d", index), nil
+}
+
+// CommitDivergence is a commit of the history returned by the gateway which does not match the chain.
+type CommitDivergence struct {
+	// Index is the position of the commit in the history.
+	Index int
+	// Gateway and Chain are the commit as returned by the gateway and as recorded on chain, commit id and height
+	// separated by \032, empty if the commit is missing on one side.
+	Gateway string
+	Chain   string
+	Reason  string
+}
+
+// CommitsReport is the outcome of VerifyCommits.
+type CommitsReport struct {
+	DataId string
+	Alias  string
+	// Commits is the history recorded on chain, from the first commit to the latest one.
+	Commits []types.MetaCommit
+	// Divergences lists the differences between the gateway and the chain, and the inconsistencies of the
+	// history on chain itself, it is empty if the history checked out.
+	Divergences []CommitDivergence
+}
+
+// CommitsError is returned by VerifyCommits when the history of a data diverges.
+// errors.Is reports true for ErrIntegrity.
+type CommitsError struct {
+	DataId      string
+	Divergences []CommitDivergence
+}
+
+func (e *CommitsError) Error() string {
+	first := e.Divergences[0]
+	return fmt.Sprintf("%s: commit history of %s has %d divergences, the first at index %d: %s",
+		ErrIntegrity, e.DataId, len(e.Divergences), first.Index, first.Reason)
+}
+
+func (e *CommitsError) Is(target error) bool {
+	return target == ErrIntegrity
+}
+
+// VerifyCommits cross-checks the commit history returned by the gateway for the given alias or data id against
+// the metadata on chain: every commit id and height must match, the alias must resolve to the data id with the
+// chain GetModel, and the history must be ordered the way UpdateModel chains commits, starting at the data id
+// with non-decreasing heights and ending at the latest commit. The report lists all the divergences found, in
+// which case it is returned along with a *CommitsError.
+func (sc *SaoClientApi) VerifyCommits(ctx context.Context, keyword string, groupId string) (*CommitsReport, error) {
+	resp, err := sc.ShowCommits(ctx, keyword, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := sc.client.GetMeta(ctx, resp.DataId)
+	if err != nil {
+		return nil, err
+	}
+	metadata := meta.Metadata
+
+	report := &CommitsReport{
+		DataId: resp.DataId,
+		Alias:  resp.Alias,
+	}
+	diverge := func(index int, gateway string, chain string, reason string, args ...interface{}) {
+		report.Divergences = append(report.Divergences, CommitDivergence{
+			Index:   index,
+			Gateway: gateway,
+			Chain:   chain,
+			Reason:  fmt.Sprintf(reason, args...),
+		})
+	}
+
+	if resp.Alias != metadata.Alias {
+		diverge(-1, "", "", "gateway returned alias %s, the chain recorded %s", resp.Alias, metadata.Alias)
+	}
+	model, err := sc.client.GetModel(ctx, fmt.Sprintf("%s-%s-%s", metadata.Owner, metadata.Alias, metadata.GroupId))
+	if err != nil {
+		return nil, err
+	}
+	if model.Model.Data != resp.DataId {
+		diverge(-1, "", "", "alias %s resolves to %s on chain, not %s", metadata.Alias, model.Model.Data, resp.DataId)
+	}
+
+	var chainCommits []string
+	for i := 0; i < len(resp.Commits) || i < len(metadata.Commits); i++ {
+		var gateway, chain string
+		if i < len(resp.Commits) {
+			gateway = resp.Commits[i]
+		}
+		if i < len(metadata.Commits) {
+			chain = metadata.Commits[i]
+		}
+
+		switch {
+		case chain == "":
+			diverge(i, gateway, chain, "commit is not recorded on chain")
+			continue
+		case gateway == "":
+			diverge(i, gateway, chain, "commit is missing from the gateway history")
+		case gateway != chain:
+			diverge(i, gateway, chain, "commit differs from the chain")
+		}
+
+		commit, err := types.ParseMetaCommit(chain)
+		if err != nil {
+			diverge(i, gateway, chain, "invalid commit on chain: %v", err)
+			continue
+		}
+		report.Commits = append(report.Commits, commit)
+		chainCommits = append(chainCommits, chain)
+	}
+
+	// UpdateModel proposes commitId|newCommitId, so every commit follows the previous one on chain.
+	seen := make(map[string]bool)
+	for i, commit := range report.Commits {
+		chain := chainCommits[i]
+		if i == 0 && commit.CommitId != metadata.DataId {
+			diverge(i, "", chain, "first commit %s is not the data id", commit.CommitId)
+		}
+		if i > 0 && commit.Height < report.Commits[i-1].Height {
+			diverge(i, "", chain, "commit height %d is lower than the previous commit height %d", commit.Height, report.Commits[i-1].Height)
+		}
+		if seen[commit.CommitId] {
+			diverge(i, "", chain, "commit %s appears more than once", commit.CommitId)
+		}
+		seen[commit.CommitId] = true
+	}
+	if n := len(report.Commits); n > 0 && report.Commits[n-1].CommitId != metadata.Commit {
+		diverge(n-1, "", chainCommits[n-1], "last commit %s is not the latest commit %s", report.Commits[n-1].CommitId, metadata.Commit)
+	}
+
+	if len(report.Divergences) > 0 {
+		return report, &CommitsError{DataId: report.DataId, Divergences: report.Divergences}
+	}
+	return report, nil
+}
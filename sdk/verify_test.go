@@ -0,0 +1,146 @@
+package sdk_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-client-go/sdktest"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// tamperingGateway appends a byte to the content it loads and drops the last commit of the histories it shows.
+type tamperingGateway struct {
+	*sdktest.Gateway
+}
+
+func (g tamperingGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	resp, err := g.Gateway.ModelLoad(ctx, req)
+	resp.Content = append(resp.Content, ' ')
+	return resp, err
+}
+
+func (g tamperingGateway) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
+	resp, err := g.Gateway.ModelShowCommits(ctx, req)
+	if len(resp.Commits) > 1 {
+		resp.Commits = resp.Commits[:len(resp.Commits)-1]
+	}
+	return resp, err
+}
+
+func newTamperedClient(srv *sdktest.Server, home string, opts ...sdk.Option) *sdk.SaoClientApi {
+	gateway := tamperingGateway{sdktest.NewGateway(srv.Store, sdktest.GatewayAddress)}
+	return sdk.NewSaoClientApiWithBackends(gateway, srv.Chain, "alice", home, opts...)
+}
+
+func TestContentVerification(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice", sdk.WithContentVerification(true))
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	if got := load(t, client, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+
+	tampered := newTamperedClient(srv, home, sdk.WithContentVerification(true))
+	_, err := tampered.Load(ctx, dataId, "", "", "g")
+	var integrityErr *sdk.IntegrityError
+	if !errors.As(err, &integrityErr) || !errors.Is(err, sdk.ErrIntegrity) || !errors.Is(err, types.ErrInvalidCid) {
+		t.Fatalf("got %v, want an IntegrityError", err)
+	}
+	if integrityErr.DataId != dataId {
+		t.Fatalf("got data id %s, want %s", integrityErr.DataId, dataId)
+	}
+
+	// downloads are always verified
+	var buf bytes.Buffer
+	_, err = newTamperedClient(srv, home).Download(ctx, dataId, &buf, sdk.DownloadOptions{GroupId: "g"})
+	if !errors.Is(err, sdk.ErrIntegrity) || buf.Len() != 0 {
+		t.Fatalf("got %d bytes, %v, want %v", buf.Len(), err, sdk.ErrIntegrity)
+	}
+}
+
+// replayingGateway answers the loads with a response it recorded, once recording is over.
+type replayingGateway struct {
+	*sdktest.Gateway
+	recorded *apitypes.LoadResp
+	replay   bool
+}
+
+func (g *replayingGateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	if g.replay {
+		return *g.recorded, nil
+	}
+	resp, err := g.Gateway.ModelLoad(ctx, req)
+	g.recorded = &resp
+	return resp, err
+}
+
+func TestContentVerificationOfTheLoadedCommit(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	otherId := createModel(t, client, `{"b":1}`, "other")
+	gateway := &replayingGateway{Gateway: sdktest.NewGateway(srv.Store, sdktest.GatewayAddress)}
+	replaying := sdk.NewSaoClientApiWithBackends(gateway, srv.Chain, "alice", home, sdk.WithContentVerification(true))
+
+	// a stale commit
+	if got := load(t, replaying, dataId); got != `{"a":1}` {
+		t.Fatalf("got %s", got)
+	}
+	if err := client.UpdateModelQuick(ctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+	gateway.replay = true
+	if _, err := replaying.Load(ctx, dataId, "", "", "g"); !errors.Is(err, sdk.ErrIntegrity) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrIntegrity)
+	}
+	// the stale commit is the one requested
+	if got, err := replaying.Load(ctx, "m", "v0", "", "g"); err != nil || string(got) != `{"a":1}` {
+		t.Fatalf("got %s, %v", got, err)
+	}
+
+	// another model
+	gateway.replay = false
+	load(t, replaying, otherId)
+	gateway.replay = true
+	if _, err := replaying.Load(ctx, "m", "", "", "g"); !errors.Is(err, sdk.ErrIntegrity) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrIntegrity)
+	}
+}
+
+func TestVerifyCommits(t *testing.T) {
+	ctx := context.Background()
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	home := t.TempDir()
+	client := newClient(t, srv, home, "alice")
+	dataId := createModel(t, client, `{"a":1}`, "m")
+	if err := client.UpdateModelQuick(ctx, dataId, []byte(`{"a":2}`), "g", 1, 100, false, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := client.VerifyCommits(ctx, "m", "g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.DataId != dataId || len(report.Commits) != 2 || len(report.Divergences) != 0 {
+		t.Fatalf("got %+v", report)
+	}
+
+	report, err = newTamperedClient(srv, home).VerifyCommits(ctx, dataId, "g")
+	if !errors.Is(err, sdk.ErrIntegrity) {
+		t.Fatalf("got %v, want %v", err, sdk.ErrIntegrity)
+	}
+	if len(report.Divergences) != 1 || report.Divergences[0].Index != 1 {
+		t.Fatalf("got %+v", report.Divergences)
+	}
+}
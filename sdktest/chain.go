@@ -0,0 +1,272 @@
+package sdktest
+
+import (
+	"context"
+	"sort"
+
+	"github.com/SaoNetwork/sao-did/sid"
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	nodetypes "github.com/SaoNetwork/sao/x/node/types"
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	"github.com/cosmos/cosmos-sdk/client"
+	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	cid "github.com/ipfs/go-cid"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Chain is an in-memory fake of chain.ChainSvcApi backed by the same Store as the fake gateway.
+// Queries are served from the store, transactions other than the ones the gateway sends are not supported.
+type Chain struct {
+	store *Store
+}
+
+var _ chain.ChainSvcApi = (*Chain)(nil)
+
+func NewChain(store *Store) *Chain {
+	return &Chain{
+		store: store,
+	}
+}
+
+func unsupported() error {
+	return types.Wrap(types.ErrUnSupport, nil)
+}
+
+func (c *Chain) Stop(ctx context.Context) error {
+	return nil
+}
+
+func (c *Chain) GetLastHeight(ctx context.Context) (int64, error) {
+	return c.store.Height(), nil
+}
+
+func (c *Chain) GetAccount(ctx context.Context, address string) (client.Account, error) {
+	return nil, unsupported()
+}
+
+func (c *Chain) GetBalance(ctx context.Context, address string) (sdktypes.Coins, error) {
+	return sdktypes.Coins{}, nil
+}
+
+func (c *Chain) GetDidInfo(ctx context.Context, did string) (types.DidInfo, error) {
+	return nil, unsupported()
+}
+
+func (c *Chain) GetFishmen(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (c *Chain) GetSidDocument(ctx context.Context, versionId string) (*sid.SidDocument, error) {
+	return nil, types.Wrap(types.ErrGetSidDocumentFailed, unsupported())
+}
+
+func (c *Chain) QueryDidParams(ctx context.Context) (string, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	return c.store.builtinDids, nil
+}
+
+func (c *Chain) UpdateDidBinding(ctx context.Context, creator string, did string, accountId string) (string, error) {
+	return "", unsupported()
+}
+
+func (c *Chain) QueryPaymentAddress(ctx context.Context, did string) (string, error) {
+	return "", unsupported()
+}
+
+func (c *Chain) QueryMetadata(ctx context.Context, req *types.MetadataProposal, height int64) (*saotypes.QueryMetadataResponse, error) {
+	err := validSignature(&req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	meta, err := c.store.resolve(req.Proposal)
+	if err != nil {
+		return nil, types.Wrap(types.ErrQueryMetadataFailed, err)
+	}
+	if !c.store.canRead(meta, req.Proposal.Owner) {
+		return nil, types.Wrapf(types.ErrQueryMetadataFailed, "%s has no permission to query %s", req.Proposal.Owner, meta.DataId)
+	}
+
+	commit, _ := meta.commitAt(height)
+	return c.store.queryMetadata(meta, commit), nil
+}
+
+func (c *Chain) GetMeta(ctx context.Context, dataId string) (*modeltypes.QueryGetMetadataResponse, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	meta, found := c.store.metas[dataId]
+	if !found {
+		return nil, types.Wrapf(types.ErrNotFound, "dataId:%s not found", dataId)
+	}
+	return &modeltypes.QueryGetMetadataResponse{
+		Metadata: meta.Metadata,
+		OrderId:  meta.OrderId,
+		Shards:   map[string]*modeltypes.ShardMeta{},
+	}, nil
+}
+
+func (c *Chain) GetModel(ctx context.Context, key string) (*modeltypes.QueryGetModelResponse, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	dataId, found := c.store.models[key]
+	if !found {
+		return nil, types.Wrapf(types.ErrNotFound, "model key: %s", key)
+	}
+	return &modeltypes.QueryGetModelResponse{
+		Model: modeltypes.Model{
+			Key:  key,
+			Data: dataId,
+		},
+	}, nil
+}
+
+func (c *Chain) UpdatePermission(ctx context.Context, signer string, proposal *types.PermissionProposal) (string, error) {
+	return "", unsupported()
+}
+
+func (c *Chain) Create(ctx context.Context, creator string) (string, error) {
+	return "", unsupported()
+}
+
+func (c *Chain) Reset(ctx context.Context, creator string, peerInfo string, status uint32, txAddresses []string, description *nodetypes.Description) (string, error) {
+	c.store.RegisterNode(creator, peerInfo)
+	return "", nil
+}
+
+func (c *Chain) GetNodePeer(ctx context.Context, creator string) (string, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	peerInfo, found := c.store.nodes[creator]
+	if !found {
+		return "", types.Wrapf(types.ErrQueryNodeFailed, "node %s not found", creator)
+	}
+	return peerInfo, nil
+}
+
+func (c *Chain) GetNodeStatus(ctx context.Context, creator string) (uint32, error) {
+	return 0, unsupported()
+}
+
+func (c *Chain) ListNodes(ctx context.Context) ([]nodetypes.Node, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	nodes := make([]nodetypes.Node, 0, len(c.store.nodes))
+	for address, peerInfo := range c.store.nodes {
+		nodes = append(nodes, nodetypes.Node{
+			Creator: address,
+			Peer:    peerInfo,
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Creator < nodes[j].Creator
+	})
+	return nodes, nil
+}
+
+func (c *Chain) StartStatusReporter(ctx context.Context, creator string, status uint32) {
+}
+
+func (c *Chain) OrderReady(ctx context.Context, provider string, orderId uint64) (saotypes.MsgReadyResponse, string, int64, error) {
+	return saotypes.MsgReadyResponse{}, "", 0, unsupported()
+}
+
+func (c *Chain) StoreOrder(ctx context.Context, signer string, clientProposal *types.OrderStoreProposal) (saotypes.MsgStoreResponse, string, int64, error) {
+	return saotypes.MsgStoreResponse{}, "", 0, unsupported()
+}
+
+func (c *Chain) CompleteOrder(ctx context.Context, creator string, orderId uint64, cid cid.Cid, size uint64) (string, int64, error) {
+	return "", 0, unsupported()
+}
+
+func (c *Chain) RenewOrder(ctx context.Context, creator string, orderRenewProposal types.OrderRenewProposal) (string, map[string]string, error) {
+	return "", nil, unsupported()
+}
+
+func (c *Chain) MigrateOrder(ctx context.Context, creator string, dataIds []string) (string, map[string]string, int64, error) {
+	return "", nil, 0, unsupported()
+}
+
+func (c *Chain) GetOrder(ctx context.Context, orderId uint64) (*ordertypes.FullOrder, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	order, found := c.store.orders[orderId]
+	if !found {
+		return nil, types.Wrapf(types.ErrQueryOrderFailed, "order:%d not found", orderId)
+	}
+	o := *order
+	return &o, nil
+}
+
+func (c *Chain) GetShard(ctx context.Context, shardId uint64) (*ordertypes.Shard, error) {
+	return nil, types.Wrapf(types.ErrQueryShardFailed, "shard %d not found", shardId)
+}
+
+func (c *Chain) TerminateOrder(ctx context.Context, creator string, terminateProposal types.OrderTerminateProposal) (string, error) {
+	return "", unsupported()
+}
+
+func (c *Chain) GetTx(ctx context.Context, hash string, heigth int64) (*coretypes.ResultTx, error) {
+	return nil, unsupported()
+}
+
+func (c *Chain) ReportFaults(ctx context.Context, creator string, provider string, faults []*saotypes.Fault) ([]string, error) {
+	return nil, unsupported()
+}
+
+func (c *Chain) RecoverFaults(ctx context.Context, creator string, provider string, faults []*saotypes.Fault) ([]string, error) {
+	return nil, unsupported()
+}
+
+func (c *Chain) ListMeta(ctx context.Context, offset uint64, limit uint64) ([]modeltypes.Metadata, uint64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	metas := c.store.sortedMetas("")
+	total := uint64(len(metas))
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return metas[offset:end], total, nil
+}
+
+func (c *Chain) ListMetaByDid(ctx context.Context, did string) ([]modeltypes.Metadata, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	return c.store.sortedMetas(did), nil
+}
+
+func (c *Chain) GetBlock(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	if height < 1 || height > c.store.height {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "height %d is not available, last height %d", height, c.store.height)
+	}
+	return &coretypes.ResultBlock{
+		Block: &tmtypes.Block{
+			Header: tmtypes.Header{
+				Height: height,
+				Time:   c.store.blockAt(height),
+			},
+		},
+	}, nil
+}
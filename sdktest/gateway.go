@@ -0,0 +1,429 @@
+package sdktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	saodid "github.com/SaoNetwork/sao-did"
+	saodidtypes "github.com/SaoNetwork/sao-did/types"
+	apitypes "github.com/SaoNetwork/sao-node/api/types"
+	types "github.com/SaoNetwork/sao-node/types"
+	utils "github.com/SaoNetwork/sao-node/utils"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+)
+
+// Gateway is an in-memory fake of the sao-node gateway API. Its exported methods are served over JSON-RPC
+// by Server, so it must not grow any method that is not part of the gateway API.
+type Gateway struct {
+	store   *Store
+	address string
+}
+
+var _ sdk.GatewayApi = (*Gateway)(nil)
+
+func NewGateway(store *Store, address string) *Gateway {
+	return &Gateway{
+		store:   store,
+		address: address,
+	}
+}
+
+func validSignature(proposal types.ConsensusProposal, owner string, signature saotypes.JwsSignature) error {
+	if owner == "all" {
+		return nil
+	}
+
+	didManager, err := saodid.NewDidManagerWithDid(owner, nil)
+	if err != nil {
+		return types.Wrap(types.ErrInvalidDid, err)
+	}
+
+	proposalBytes, err := proposal.Marshal()
+	if err != nil {
+		return types.Wrap(types.ErrMarshalFailed, err)
+	}
+
+	_, err = didManager.VerifyJWS(saodidtypes.GeneralJWS{
+		Payload: base64.RawURLEncoding.EncodeToString(proposalBytes),
+		Signatures: []saodidtypes.JwsSignature{
+			saodidtypes.JwsSignature(signature),
+		},
+	})
+	if err != nil {
+		return types.Wrap(types.ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// validQuery checks the signature and the LastValidHeight of a query request.
+func (g *Gateway) validQuery(req *types.MetadataProposal) error {
+	err := validSignature(&req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return err
+	}
+
+	height := g.store.Height()
+	if req.Proposal.LastValidHeight < uint64(height) {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid query, LastValidHeight:%d > now:%d", req.Proposal.LastValidHeight, height)
+	}
+	return nil
+}
+
+func (g *Gateway) validStoreProposal(req *types.MetadataProposal, orderProposal *types.OrderStoreProposal) error {
+	err := g.validQuery(req)
+	if err != nil {
+		return err
+	}
+	err = validSignature(&orderProposal.Proposal, orderProposal.Proposal.Owner, orderProposal.JwsSignature)
+	if err != nil {
+		return err
+	}
+	if orderProposal.Proposal.Timeout == 0 {
+		return types.Wrapf(types.ErrInvalidParameters, "invalid arguments: timeout")
+	}
+	return nil
+}
+
+func (g *Gateway) txId() string {
+	return fmt.Sprintf("%064X", g.store.height)
+}
+
+func (g *Gateway) create(orderProposal *types.OrderStoreProposal, content []byte) (apitypes.CreateResp, error) {
+	proposal := orderProposal.Proposal
+	if len(proposal.DataId) != 36 {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidDataId, "dataid: %s", proposal.DataId)
+	}
+	if proposal.Provider != g.address {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidProvider, "provider: %s", proposal.Provider)
+	}
+	if content != nil {
+		if uint64(len(content)) != proposal.Size_ {
+			return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidContent, "given size(%d) doesn't match content size(%d)", proposal.Size_, len(content))
+		}
+		contentCid, err := sdk.CalculateCid(content)
+		if err != nil {
+			return apitypes.CreateResp{}, err
+		}
+		if contentCid.String() != proposal.Cid {
+			return apitypes.CreateResp{}, types.Wrapf(types.ErrInvalidCid, "cid mismatch, expected %s, but got %s", proposal.Cid, contentCid)
+		}
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	if _, found := g.store.metas[proposal.DataId]; found {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrConflictId, "dataId: %s", proposal.DataId)
+	}
+	key := modelKey(proposal.Owner, proposal.Alias, proposal.GroupId)
+	if _, found := g.store.models[key]; found {
+		return apitypes.CreateResp{}, types.Wrapf(types.ErrConflictName, "model key: %s", key)
+	}
+
+	if content != nil {
+		g.store.blobs[proposal.Cid] = content
+	}
+
+	meta := &metadata{
+		Metadata: modeltypes.Metadata{
+			DataId:        proposal.DataId,
+			Owner:         proposal.Owner,
+			Alias:         proposal.Alias,
+			GroupId:       proposal.GroupId,
+			Tags:          proposal.Tags,
+			ExtendInfo:    proposal.ExtendInfo,
+			Rule:          proposal.Rule,
+			Duration:      proposal.Duration,
+			CreatedAt:     uint64(g.store.height),
+			ReadonlyDids:  proposal.ReadonlyDids,
+			ReadwriteDids: proposal.ReadwriteDids,
+			Status:        modeltypes.MetaComplete,
+		},
+	}
+	txId := g.txId()
+	g.store.appendCommit(meta, g.store.newOrder(g.address, proposal, proposal.CommitId))
+	g.store.metas[proposal.DataId] = meta
+	g.store.models[key] = proposal.DataId
+
+	return apitypes.CreateResp{
+		DataId: proposal.DataId,
+		Alias:  proposal.Alias,
+		TxId:   txId,
+		Cid:    proposal.Cid,
+	}, nil
+}
+
+func (g *Gateway) ModelCreate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, content []byte) (apitypes.CreateResp, error) {
+	err := g.validStoreProposal(req, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	return g.create(orderProposal, content)
+}
+
+func (g *Gateway) ModelCreateFile(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64) (apitypes.CreateResp, error) {
+	err := g.validStoreProposal(req, orderProposal)
+	if err != nil {
+		return apitypes.CreateResp{}, err
+	}
+	return g.create(orderProposal, nil)
+}
+
+func (g *Gateway) ModelLoad(ctx context.Context, req *types.MetadataProposal) (apitypes.LoadResp, error) {
+	err := g.validQuery(req)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	meta, err := g.store.resolve(req.Proposal)
+	if err != nil {
+		return apitypes.LoadResp{}, err
+	}
+	if !g.store.canRead(meta, req.Proposal.Owner) {
+		return apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidDid, "%s has no permission to load %s", req.Proposal.Owner, meta.DataId)
+	}
+
+	c, index := meta.commitAt(0)
+	if req.Proposal.Version != "" {
+		match, err := regexp.Match(`^v\d+$`, []byte(req.Proposal.Version))
+		if err != nil || !match {
+			return apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidVersion, "invalid Version: %s", req.Proposal.Version)
+		}
+		index, err = strconv.Atoi(strings.TrimPrefix(req.Proposal.Version, "v"))
+		if err != nil || index >= len(meta.history) {
+			return apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidVersion, "invalid Version: %s", req.Proposal.Version)
+		}
+		c = meta.history[index]
+	}
+	if req.Proposal.CommitId != "" {
+		var found bool
+		c, index, found = meta.findCommit(req.Proposal.CommitId)
+		if !found {
+			return apitypes.LoadResp{}, types.Wrapf(types.ErrInvalidCommitInfo, "invalid commitId: %s", req.Proposal.CommitId)
+		}
+	}
+
+	content, found := g.store.blobs[c.Cid]
+	if !found {
+		return apitypes.LoadResp{}, types.Wrapf(types.ErrDataMissing, "cid: %s", c.Cid)
+	}
+
+	return apitypes.LoadResp{
+		DataId:   meta.DataId,
+		Alias:    meta.Alias,
+		CommitId: c.CommitId,
+		Version:  fmt.Sprintf("v%d", index),
+		Cid:      c.Cid,
+		Content:  content,
+	}, nil
+}
+
+func (g *Gateway) ModelDelete(ctx context.Context, req *types.OrderTerminateProposal, isPublish bool) (apitypes.DeleteResp, error) {
+	err := validSignature(&req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return apitypes.DeleteResp{}, err
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	meta, found := g.store.metas[req.Proposal.DataId]
+	if !found {
+		return apitypes.DeleteResp{}, types.Wrapf(types.ErrNotFound, "dataId:%s not found", req.Proposal.DataId)
+	}
+	if meta.Owner != req.Proposal.Owner {
+		return apitypes.DeleteResp{}, types.Wrapf(types.ErrInvalidDid, "%s has no permission to delete %s", req.Proposal.Owner, meta.DataId)
+	}
+
+	if isPublish {
+		for _, orderId := range meta.Orders {
+			if order, found := g.store.orders[orderId]; found {
+				order.Status = ordertypes.OrderTerminated
+			}
+		}
+		delete(g.store.models, modelKey(meta.Owner, meta.Alias, meta.GroupId))
+		delete(g.store.metas, meta.DataId)
+		g.store.height++
+	}
+
+	return apitypes.DeleteResp{
+		DataId: meta.DataId,
+		Alias:  meta.Alias,
+	}, nil
+}
+
+func (g *Gateway) ModelShowCommits(ctx context.Context, req *types.MetadataProposal) (apitypes.ShowCommitsResp, error) {
+	err := g.validQuery(req)
+	if err != nil {
+		return apitypes.ShowCommitsResp{}, err
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	meta, err := g.store.resolve(req.Proposal)
+	if err != nil {
+		return apitypes.ShowCommitsResp{}, err
+	}
+	if !g.store.canRead(meta, req.Proposal.Owner) {
+		return apitypes.ShowCommitsResp{}, types.Wrapf(types.ErrInvalidDid, "%s has no permission to load %s", req.Proposal.Owner, meta.DataId)
+	}
+
+	return apitypes.ShowCommitsResp{
+		DataId:  meta.DataId,
+		Alias:   meta.Alias,
+		Commits: append([]string{}, meta.Commits...),
+	}, nil
+}
+
+func (g *Gateway) ModelUpdate(ctx context.Context, req *types.MetadataProposal, orderProposal *types.OrderStoreProposal, orderId uint64, patch []byte) (apitypes.UpdateResp, error) {
+	err := g.validStoreProposal(req, orderProposal)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	proposal := orderProposal.Proposal
+
+	commitIds := strings.Split(proposal.CommitId, "|")
+	if len(commitIds) != 2 {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidCommitInfo, "invalid commitId:%s", proposal.CommitId)
+	}
+	lastCommitId, commitId := commitIds[0], commitIds[1]
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	meta, err := g.store.resolve(req.Proposal)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	if !g.store.canWrite(meta, proposal.Owner) {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidDid, "%s has no permission to update %s", proposal.Owner, meta.DataId)
+	}
+	if lastCommitId != meta.Commit {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidCommitInfo, "commit %s is not the latest commit %s", lastCommitId, meta.Commit)
+	}
+
+	newContent, err := utils.ApplyPatch(g.store.blobs[meta.Cid], patch)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	if bytes.Equal(g.store.blobs[meta.Cid], newContent) {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidContent, "no content updated.")
+	}
+	if len(newContent) != int(proposal.Size_) {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidContent, "given size(%d) doesn't match target content size(%d)", int(proposal.Size_), len(newContent))
+	}
+	newContentCid, err := sdk.CalculateCid(newContent)
+	if err != nil {
+		return apitypes.UpdateResp{}, err
+	}
+	if newContentCid.String() != proposal.Cid {
+		return apitypes.UpdateResp{}, types.Wrapf(types.ErrInvalidCid, "cid mismatch, expected %s, but got %s", proposal.Cid, newContentCid)
+	}
+
+	g.store.blobs[proposal.Cid] = newContent
+	if proposal.Operation == 2 {
+		g.store.dropLastCommit(meta)
+	}
+	txId := g.txId()
+	g.store.appendCommit(meta, g.store.newOrder(g.address, proposal, commitId))
+
+	return apitypes.UpdateResp{
+		DataId:   meta.DataId,
+		CommitId: commitId,
+		Alias:    meta.Alias,
+		TxId:     txId,
+		Cid:      proposal.Cid,
+	}, nil
+}
+
+func (g *Gateway) ModelRenewOrder(ctx context.Context, req *types.OrderRenewProposal, isPublish bool) (apitypes.RenewResp, error) {
+	err := validSignature(&req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return apitypes.RenewResp{}, err
+	}
+	if !isPublish {
+		return apitypes.RenewResp{}, nil
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	results := make(map[string]string, len(req.Proposal.Data))
+	for _, dataId := range req.Proposal.Data {
+		meta, found := g.store.metas[dataId]
+		if !found {
+			results[dataId] = fmt.Sprintf("rpc error: code = NotFound desc = FAILED: dataId %s not found", dataId)
+			continue
+		}
+		if meta.Owner != req.Proposal.Owner {
+			results[dataId] = fmt.Sprintf("FAILED: no permission to renew the model %s: no permission", dataId)
+			continue
+		}
+
+		last := g.store.orders[meta.OrderId]
+		order := g.store.newOrder(g.address, saotypes.Proposal{
+			Owner:     meta.Owner,
+			Cid:       last.Cid,
+			Duration:  req.Proposal.Duration,
+			Replica:   last.Replica,
+			Size_:     last.Size_,
+			Operation: 3,
+			Timeout:   req.Proposal.Timeout,
+			DataId:    dataId,
+		}, last.Commit)
+		meta.Duration += req.Proposal.Duration
+		results[dataId] = fmt.Sprintf("SUCCESS: orderId=%d", order.Id)
+	}
+	g.store.height++
+
+	return apitypes.RenewResp{
+		Results: results,
+	}, nil
+}
+
+func (g *Gateway) ModelUpdatePermission(ctx context.Context, req *types.PermissionProposal, isPublish bool) (apitypes.UpdatePermissionResp, error) {
+	err := validSignature(&req.Proposal, req.Proposal.Owner, req.JwsSignature)
+	if err != nil {
+		return apitypes.UpdatePermissionResp{}, err
+	}
+
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	meta, found := g.store.metas[req.Proposal.DataId]
+	if !found {
+		return apitypes.UpdatePermissionResp{}, types.Wrapf(types.ErrNotFound, "dataId:%s not found", req.Proposal.DataId)
+	}
+	if meta.Owner != req.Proposal.Owner {
+		return apitypes.UpdatePermissionResp{}, types.Wrapf(types.ErrInvalidDid, "%s has no permission to update %s", req.Proposal.Owner, meta.DataId)
+	}
+
+	if isPublish {
+		meta.ReadonlyDids = req.Proposal.ReadonlyDids
+		meta.ReadwriteDids = req.Proposal.ReadwriteDids
+		g.store.height++
+	}
+
+	return apitypes.UpdatePermissionResp{
+		DataId: meta.DataId,
+	}, nil
+}
+
+func (g *Gateway) GetNodeAddress(ctx context.Context) (string, error) {
+	return g.address, nil
+}
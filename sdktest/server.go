@@ -0,0 +1,111 @@
+package sdktest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-node/chain"
+	"github.com/filecoin-project/go-jsonrpc"
+)
+
+const (
+	// GatewayAddress is the chain address reported by the fake gateway's GetNodeAddress.
+	GatewayAddress = "sao1fakegateway0000000000000000000000000000"
+	// GatewayPeerInfo is the peer info registered on the fake chain for GatewayAddress, a tcp and a webtransport
+	// address like the ones of a sao-node gateway.
+	GatewayPeerInfo = "/ip4/127.0.0.1/tcp/5153/p2p/12D3KooWFakeGateway," +
+		"/ip4/127.0.0.1/udp/5154/quic/webtransport/p2p/12D3KooWFakeGateway"
+)
+
+// Server serves a fake gateway over JSON-RPC from an httptest server, next to a fake chain sharing its state.
+type Server struct {
+	Store     *Store
+	Gateway   *Gateway
+	Chain     *Chain
+	Transport *Transport
+	// URL is the JSON-RPC endpoint of the fake gateway, to be used as the node endpoint.
+	URL string
+
+	httpServer *httptest.Server
+
+	mu       sync.Mutex
+	failures int
+}
+
+func NewServer() *Server {
+	return NewServerWithStore(NewStore(), GatewayAddress, GatewayPeerInfo)
+}
+
+// NewServerWithStore serves another fake gateway with the given address over an existing store,
+// e.g. to test the failover between several gateways.
+func NewServerWithStore(store *Store, address string, peerInfo string) *Server {
+	store.RegisterNode(address, peerInfo)
+	gateway := NewGateway(store, address)
+
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("Sao", gateway)
+
+	s := &Server{
+		Store:     store,
+		Gateway:   gateway,
+		Chain:     NewChain(store),
+		Transport: NewTransport(store),
+	}
+	s.Transport.gateway = gateway
+	s.httpServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail() {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		rpcServer.ServeHTTP(w, r)
+	}))
+	s.URL = s.httpServer.URL + "/rpc/v0"
+	return s
+}
+
+// FailRequests makes the next n gateway requests fail with 502 Bad Gateway, as an unavailable gateway would.
+func (s *Server) FailRequests(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = n
+}
+
+func (s *Server) fail() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures <= 0 {
+		return false
+	}
+	s.failures--
+	return true
+}
+
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// NewClient creates a SaoClientApi talking JSON-RPC to the fake gateway and using the fake chain and file transport.
+// The key must exist in the test keyring under keyringHome, see CreateAccount.
+func (s *Server) NewClient(ctx context.Context, keyName string, keyringHome string, opts ...sdk.Option) (*sdk.SaoClientApi, error) {
+	gatewayApi, closer, err := sdk.NewNodeApi(ctx, s.URL, "default token")
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]sdk.Option{sdk.WithFileTransport(s.Transport)}, opts...)
+	client := sdk.NewSaoClientApiWithBackends(gatewayApi, s.Chain, keyName, keyringHome, opts...)
+	client.NodeEndpoint = s.URL
+	client.Closer = closer
+	return client, nil
+}
+
+// CreateAccount creates a new key in the test keyring under keyringHome and returns its address.
+func CreateAccount(ctx context.Context, keyringHome string, keyName string) (string, error) {
+	_, address, _, err := chain.Create(ctx, keyringHome, keyName)
+	return address, err
+}
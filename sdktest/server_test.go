@@ -0,0 +1,133 @@
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+func TestServerFailRequests(t *testing.T) {
+	ctx := context.Background()
+	srv := NewServer()
+	defer srv.Close()
+	gateway, closer, err := sdk.NewNodeApi(ctx, srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+
+	srv.FailRequests(2)
+	for i := 0; i < 2; i++ {
+		if _, err := gateway.GetNodeAddress(ctx); err == nil {
+			t.Fatalf("request %d succeeded", i)
+		}
+	}
+	address, err := gateway.GetNodeAddress(ctx)
+	if err != nil || address != GatewayAddress {
+		t.Fatalf("got %q, %v", address, err)
+	}
+}
+
+func TestChainBlocks(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	chain := NewChain(store)
+	store.SetBlockTime(6 * time.Second)
+	store.AdvanceHeight(10)
+
+	height, err := chain.GetLastHeight(ctx)
+	if err != nil || height != store.Height() {
+		t.Fatalf("got %d, %v, want %d", height, err, store.Height())
+	}
+	first, err := chain.GetBlock(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := chain.GetBlock(ctx, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := last.Block.Time.Sub(first.Block.Time); got != time.Duration(height-1)*6*time.Second {
+		t.Fatalf("got %s between the first and the last block", got)
+	}
+	if _, err := chain.GetBlock(ctx, height+1); !errors.Is(err, types.ErrInvalidParameters) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidParameters)
+	}
+
+	if _, err := chain.GetNodePeer(ctx, GatewayAddress); !errors.Is(err, types.ErrQueryNodeFailed) {
+		t.Fatalf("got %v, want %v", err, types.ErrQueryNodeFailed)
+	}
+	store.RegisterNode(GatewayAddress, GatewayPeerInfo)
+	peerInfo, err := chain.GetNodePeer(ctx, GatewayAddress)
+	if err != nil || peerInfo != GatewayPeerInfo {
+		t.Fatalf("got %q, %v", peerInfo, err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	store.RegisterNode(GatewayAddress, GatewayPeerInfo)
+	transport := NewTransport(store)
+	const (
+		tcp = "/ip4/127.0.0.1/tcp/5153/p2p/12D3KooWFakeGateway"
+		udp = "/ip4/127.0.0.1/udp/5154/quic/webtransport/p2p/12D3KooWFakeGateway"
+	)
+
+	for _, dial := range []struct {
+		multiaddr string
+		protocol  string
+		err       error
+	}{
+		{tcp, "tcp", nil},
+		{udp, "udp", nil},
+		{tcp, "udp", types.ErrConnectFailed},
+		{udp, "tcp", types.ErrConnectFailed},
+		{GatewayPeerInfo, "tcp", types.ErrConnectFailed},
+		{"/ip4/10.0.0.1/tcp/5153/p2p/12D3KooWOther", "tcp", types.ErrConnectFailed},
+		{tcp, "quic", types.ErrInvalidParameters},
+	} {
+		_, err := transport.Dial(ctx, dial.multiaddr, dial.protocol)
+		if (dial.err == nil && err != nil) || !errors.Is(err, dial.err) {
+			t.Errorf("%s over %s: got %v, want %v", dial.multiaddr, dial.protocol, err, dial.err)
+		}
+	}
+
+	conn, err := transport.Dial(ctx, tcp, "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	content := []byte("hello world")
+	contentCid, _ := sdk.CalculateCid(content)
+	send := func(chunkId int, chunk []byte, chunkCid string) error {
+		_, err := conn.SendChunk(ctx, &types.FileChunkReq{
+			Cid:         contentCid.String(),
+			TotalLength: len(content),
+			ChunkId:     chunkId,
+			ChunkCid:    chunkCid,
+			Content:     chunk,
+		})
+		return err
+	}
+	for i, chunk := range [][]byte{content[:5], content[5:]} {
+		chunkCid, _ := sdk.CalculateCid(chunk)
+		if err := send(i, chunk, chunkCid.String()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := send(2, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := store.Blob(contentCid.String()); !ok || string(got) != string(content) {
+		t.Fatalf("got %q, %v", got, ok)
+	}
+
+	if err := send(0, content, contentCid.String()+"x"); !errors.Is(err, types.ErrInvalidCid) {
+		t.Fatalf("got %v, want %v", err, types.ErrInvalidCid)
+	}
+}
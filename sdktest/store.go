@@ -0,0 +1,316 @@
+package sdktest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	"github.com/SaoNetwork/sao-node/chain"
+	types "github.com/SaoNetwork/sao-node/types"
+	modeltypes "github.com/SaoNetwork/sao/x/model/types"
+	ordertypes "github.com/SaoNetwork/sao/x/order/types"
+	saotypes "github.com/SaoNetwork/sao/x/sao/types"
+	cid "github.com/ipfs/go-cid"
+)
+
+// commitSep separates the commit id and the height in a metadata commit, see types.ParseMetaCommit.
+const commitSep = "\032"
+
+type commit struct {
+	CommitId string
+	Height   int64
+	Cid      string
+	Size     uint64
+	OrderId  uint64
+}
+
+type metadata struct {
+	modeltypes.Metadata
+	history []commit
+}
+
+// genesisTime is the time of the first block of the fake chain.
+var genesisTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Store is the in-memory state shared by the fake gateway and the fake chain.
+type Store struct {
+	mu          sync.Mutex
+	height      int64
+	blockTime   time.Duration
+	nextOrderId uint64
+	builtinDids string
+	metas       map[string]*metadata
+	models      map[string]string
+	orders      map[uint64]*ordertypes.FullOrder
+	blobs       map[string][]byte
+	nodes       map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{
+		height:      1,
+		blockTime:   chain.Blocktime,
+		nextOrderId: 1,
+		metas:       make(map[string]*metadata),
+		models:      make(map[string]string),
+		orders:      make(map[uint64]*ordertypes.FullOrder),
+		blobs:       make(map[string][]byte),
+		nodes:       make(map[string]string),
+	}
+}
+
+// Height returns the current height of the fake chain.
+func (s *Store) Height() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.height
+}
+
+// AdvanceHeight moves the fake chain forward by n blocks.
+func (s *Store) AdvanceHeight(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.height += n
+}
+
+// SetBlockTime sets the time between two blocks of the fake chain, as seen by GetBlock.
+func (s *Store) SetBlockTime(blockTime time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blockTime = blockTime
+}
+
+// blockAt returns the time of the block at the given height, the caller must hold the lock.
+func (s *Store) blockAt(height int64) time.Time {
+	return genesisTime.Add(time.Duration(height-1) * s.blockTime)
+}
+
+// SetBuiltinDids sets the comma separated did list returned by QueryDidParams.
+func (s *Store) SetBuiltinDids(dids string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.builtinDids = dids
+}
+
+// RegisterNode registers a node address and its peer info, as returned by GetNodePeer.
+func (s *Store) RegisterNode(address string, peerInfo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nodes[address] = peerInfo
+}
+
+// hasPeer tells whether multiaddr is one of the addresses a node registered.
+func (s *Store) hasPeer(multiaddr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.nodes {
+		for _, addr := range strings.Split(p, ",") {
+			if addr == multiaddr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PutBlob stores content out of band, as the file transport would, and returns its cid.
+func (s *Store) PutBlob(content []byte) (cid.Cid, error) {
+	c, err := sdk.CalculateCid(content)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blobs[c.String()] = content
+	return c, nil
+}
+
+// Blob returns the content stored under the given cid.
+func (s *Store) Blob(c string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, ok := s.blobs[c]
+	return content, ok
+}
+
+func modelKey(owner string, alias string, groupId string) string {
+	return fmt.Sprintf("%s-%s-%s", owner, alias, groupId)
+}
+
+// resolve looks up the metadata addressed by a query proposal, the caller must hold the lock.
+func (s *Store) resolve(proposal saotypes.QueryProposal) (*metadata, error) {
+	dataId := proposal.Keyword
+	if proposal.KeywordType > 1 {
+		owner := proposal.DataOwner
+		if owner == "" {
+			owner = proposal.Owner
+		}
+		id, found := s.models[modelKey(owner, proposal.Keyword, proposal.GroupId)]
+		if !found {
+			return nil, types.Wrapf(types.ErrNotFound, "dataId not found by Alias: %s", proposal.Keyword)
+		}
+		dataId = id
+	}
+
+	meta, found := s.metas[dataId]
+	if !found {
+		return nil, types.Wrapf(types.ErrNotFound, "dataId:%s not found", dataId)
+	}
+	return meta, nil
+}
+
+// sortedMetas lists the stored metadata ordered by dataId, optionally filtered by owner, the caller must hold the lock.
+func (s *Store) sortedMetas(owner string) []modeltypes.Metadata {
+	metas := make([]modeltypes.Metadata, 0, len(s.metas))
+	for _, meta := range s.metas {
+		if owner == "" || meta.Owner == owner {
+			metas = append(metas, meta.Metadata)
+		}
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].DataId < metas[j].DataId
+	})
+	return metas
+}
+
+func (s *Store) canRead(meta *metadata, did string) bool {
+	if did == "all" || meta.Owner == did {
+		return true
+	}
+	for _, dids := range [][]string{meta.ReadwriteDids, meta.ReadonlyDids} {
+		for _, d := range dids {
+			if d == did || (s.builtinDids != "" && strings.Contains(s.builtinDids, d)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Store) canWrite(meta *metadata, did string) bool {
+	if meta.Owner == did {
+		return true
+	}
+	for _, d := range meta.ReadwriteDids {
+		if d == did {
+			return true
+		}
+	}
+	return false
+}
+
+// commitAt returns the latest commit at or below height, or the latest commit if height is 0.
+func (meta *metadata) commitAt(height int64) (commit, int) {
+	index := len(meta.history) - 1
+	if height > 0 {
+		for index > 0 && meta.history[index].Height > height {
+			index--
+		}
+	}
+	return meta.history[index], index
+}
+
+func (meta *metadata) findCommit(commitId string) (commit, int, bool) {
+	for i, c := range meta.history {
+		if c.CommitId == commitId {
+			return c, i, true
+		}
+	}
+	return commit{}, 0, false
+}
+
+// newOrder records a completed order for the proposal, the caller must hold the lock.
+func (s *Store) newOrder(provider string, proposal saotypes.Proposal, commitId string) *ordertypes.FullOrder {
+	order := &ordertypes.FullOrder{
+		Creator:   provider,
+		Owner:     proposal.Owner,
+		Id:        s.nextOrderId,
+		Provider:  provider,
+		Cid:       proposal.Cid,
+		Duration:  proposal.Duration,
+		Status:    ordertypes.OrderCompleted,
+		Replica:   proposal.Replica,
+		Size_:     proposal.Size_,
+		Operation: proposal.Operation,
+		CreatedAt: uint64(s.height),
+		Timeout:   uint64(proposal.Timeout),
+		DataId:    proposal.DataId,
+		Commit:    commitId,
+	}
+	s.orders[order.Id] = order
+	s.nextOrderId++
+	return order
+}
+
+// appendCommit adds a commit to the metadata and moves the chain to the next block, the caller must hold the lock.
+func (s *Store) appendCommit(meta *metadata, order *ordertypes.FullOrder) {
+	c := commit{
+		CommitId: order.Commit,
+		Height:   s.height,
+		Cid:      order.Cid,
+		Size:     order.Size_,
+		OrderId:  order.Id,
+	}
+	meta.history = append(meta.history, c)
+	meta.Commits = append(meta.Commits, fmt.Sprintf("%s%s%d", c.CommitId, commitSep, c.Height))
+	meta.Commit = c.CommitId
+	meta.Cid = c.Cid
+	meta.OrderId = c.OrderId
+	meta.Orders = append(meta.Orders, c.OrderId)
+	s.height++
+}
+
+// dropLastCommit removes the latest commit, as a force update does, the caller must hold the lock.
+func (s *Store) dropLastCommit(meta *metadata) {
+	last := meta.history[len(meta.history)-1]
+	if order, found := s.orders[last.OrderId]; found {
+		order.Status = ordertypes.OrderTerminated
+	}
+	meta.history = meta.history[:len(meta.history)-1]
+	meta.Commits = meta.Commits[:len(meta.Commits)-1]
+	meta.Orders = meta.Orders[:len(meta.Orders)-1]
+}
+
+// queryMetadata converts the stored metadata into the chain query response as of the given commit.
+func (s *Store) queryMetadata(meta *metadata, c commit) *saotypes.QueryMetadataResponse {
+	resp := &saotypes.QueryMetadataResponse{
+		Metadata: saotypes.Metadata{
+			DataId:     meta.DataId,
+			Owner:      meta.Owner,
+			Alias:      meta.Alias,
+			GroupId:    meta.GroupId,
+			OrderId:    c.OrderId,
+			Tags:       meta.Tags,
+			Cid:        c.Cid,
+			Commits:    meta.Commits,
+			ExtendInfo: meta.ExtendInfo,
+			Update:     len(meta.history) > 1,
+			Commit:     c.CommitId,
+			Rule:       meta.Rule,
+			Duration:   meta.Duration,
+			CreatedAt:  meta.CreatedAt,
+			Size_:      c.Size,
+		},
+		Shards: map[string]*saotypes.ShardMeta{},
+	}
+	if order, found := s.orders[c.OrderId]; found {
+		resp.Metadata.Provider = order.Provider
+		resp.Metadata.Expire = int32(order.CreatedAt + order.Timeout)
+		resp.Metadata.Status = order.Status
+		resp.Metadata.Replica = order.Replica
+		resp.Metadata.Operation = order.Operation
+	}
+	return resp
+}
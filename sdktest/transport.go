@@ -0,0 +1,130 @@
+package sdktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/SaoNetwork/sao-client-go/sdk"
+	types "github.com/SaoNetwork/sao-node/types"
+)
+
+// Transport is a fake file transport which puts the uploaded files into the store, as PutBlob does, and streams
+// the loads of the gateway of its Server. It accepts the addresses in the peer infos of the registered nodes as
+// multiaddr.
+type Transport struct {
+	store   *Store
+	gateway *Gateway
+
+	mu      sync.Mutex
+	uploads map[string]*bytes.Buffer
+}
+
+var (
+	_ sdk.FileTransport         = (*Transport)(nil)
+	_ sdk.FileTransportStreamer = (*transportConn)(nil)
+)
+
+func NewTransport(store *Store) *Transport {
+	return &Transport{
+		store:   store,
+		uploads: make(map[string]*bytes.Buffer),
+	}
+}
+
+func (t *Transport) Dial(_ context.Context, multiaddr string, protocol string) (sdk.FileTransportConn, error) {
+	if protocol != "udp" && protocol != "tcp" {
+		return nil, types.Wrapf(types.ErrInvalidParameters, "invalid transport %s, expect udp or tcp", protocol)
+	}
+	if !t.store.hasPeer(multiaddr) {
+		return nil, types.Wrapf(types.ErrConnectFailed, "unknown peer %s", multiaddr)
+	}
+	if (protocol == "udp") != strings.Contains(multiaddr, "/udp/") {
+		return nil, types.Wrapf(types.ErrConnectFailed, "cannot dial %s over %s", multiaddr, protocol)
+	}
+	return &transportConn{transport: t}, nil
+}
+
+type transportConn struct {
+	transport *Transport
+}
+
+func (c *transportConn) SendChunk(ctx context.Context, chunk *types.FileChunkReq) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	t := c.transport
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	upload, ok := t.uploads[chunk.Cid]
+	if !ok || chunk.ChunkId == 0 {
+		upload = &bytes.Buffer{}
+		t.uploads[chunk.Cid] = upload
+	}
+
+	if len(chunk.Content) > 0 {
+		chunkCid, err := sdk.CalculateCid(chunk.Content)
+		if err != nil {
+			return "", err
+		}
+		if chunkCid.String() != chunk.ChunkCid {
+			return "", types.Wrapf(types.ErrInvalidCid, "chunk %d has cid %s, expected %s", chunk.ChunkId, chunkCid, chunk.ChunkCid)
+		}
+		upload.Write(chunk.Content)
+		return chunk.ChunkCid, nil
+	}
+
+	delete(t.uploads, chunk.Cid)
+	if upload.Len() != chunk.TotalLength {
+		return "", types.Wrapf(types.ErrInvalidParameters, "received %d bytes, expected %d", upload.Len(), chunk.TotalLength)
+	}
+	contentCid, err := t.store.PutBlob(upload.Bytes())
+	if err != nil {
+		return "", err
+	}
+	if contentCid.String() != chunk.Cid {
+		return "", types.Wrapf(types.ErrInvalidCid, "content has cid %s, expected %s", contentCid, chunk.Cid)
+	}
+	return chunk.Cid, nil
+}
+
+// Stream serves the Sao.ModelLoad requests like the rpc handler of the sao-node gateways: the response is empty
+// when the load fails.
+func (c *transportConn) Stream(ctx context.Context, req *types.RpcReq) (io.ReadCloser, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var resp types.RpcResp
+	if req.Method == "Sao.ModelLoad" && len(req.Params) == 1 && c.transport.gateway != nil {
+		var proposal types.MetadataProposal
+		err := json.Unmarshal([]byte(req.Params[0]), &proposal)
+		if err == nil {
+			loaded, err := c.transport.gateway.ModelLoad(ctx, &proposal)
+			if err == nil {
+				data, err := json.Marshal(loaded)
+				if err != nil {
+					return nil, types.Wrap(types.ErrMarshalFailed, err)
+				}
+				resp.Data = string(data)
+			}
+		}
+	} else {
+		resp.Error = "N/a"
+	}
+
+	buf, err := json.Marshal(resp)
+	if err != nil {
+		return nil, types.Wrap(types.ErrMarshalFailed, err)
+	}
+	return io.NopCloser(bytes.NewReader(buf)), nil
+}
+
+func (c *transportConn) Close() error {
+	return nil
+}